@@ -2,6 +2,8 @@ package ai
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"time"
 )
 
@@ -11,14 +13,20 @@ const (
 )
 
 func Run() {
+	RunFromIO(os.Stdin, os.Stdout)
+}
+
+// RunFromIO is Run() but with the bot talking over the given streams instead of stdin / stdout.
+
+func RunFromIO(r io.Reader, w io.Writer) {
 
-	game := NewGame()
+	game := NewGameFromIO(r, w)
 
 	game.StartLog(fmt.Sprintf("log%d.txt", game.Pid()))
 	game.LogWithoutTurn("--------------------------------------------------------------------------------")
 	game.LogWithoutTurn("%s %s starting up at %s", NAME, VERSION, time.Now().Format("2006-01-02T15:04:05Z"))
 
-	fmt.Printf("%s %s\n", NAME, VERSION)
+	fmt.Fprintf(w, "%s %s\n", NAME, VERSION)
 
 	for {
 		game.Parse()
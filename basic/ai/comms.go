@@ -3,6 +3,7 @@ package ai
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strconv"
@@ -19,8 +20,12 @@ type TokenParser struct {
 }
 
 func NewTokenParser() *TokenParser {
+	return NewTokenParserFromReader(os.Stdin)
+}
+
+func NewTokenParserFromReader(r io.Reader) *TokenParser {
 	ret := new(TokenParser)
-	ret.scanner = bufio.NewScanner(os.Stdin)
+	ret.scanner = bufio.NewScanner(r)
 	ret.scanner.Split(bufio.ScanWords)
 	return ret
 }
@@ -313,6 +318,5 @@ func (self *Game) Send() {
 		commands = append(commands, s)
 	}
 	out := strings.Join(commands, " ")
-	fmt.Printf(out)
-	fmt.Printf("\n")
+	fmt.Fprintf(self.writer, "%s\n", out)
 }
@@ -1,6 +1,8 @@
 package ai
 
 import (
+	"io"
+	"os"
 	"time"
 )
 
@@ -23,6 +25,7 @@ type Game struct {
 
 	logfile					*Logfile
 	token_parser			*TokenParser
+	writer					io.Writer
 	raw						string				// The raw input line sent by halite.exe
 
 	parse_time				time.Time
@@ -39,9 +42,17 @@ type Game struct {
 }
 
 func NewGame() *Game {
+	return NewGameFromIO(os.Stdin, os.Stdout)
+}
+
+// NewGameFromIO reads frames from r and sends orders to w, so that the bot can be
+// run in-process alongside others.
+
+func NewGameFromIO(r io.Reader, w io.Writer) *Game {
 	game := new(Game)
 	game.turn = -1
-	game.token_parser = NewTokenParser()
+	game.token_parser = NewTokenParserFromReader(r)
+	game.writer = w
 	game.pid = game.token_parser.Int()
 	game.width = game.token_parser.Int()
 	game.height = game.token_parser.Int()
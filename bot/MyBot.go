@@ -3,8 +3,10 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"runtime/debug"
 	"runtime/pprof"
 	"strings"
 	"time"
@@ -23,23 +25,21 @@ func main() {
 
 	config := new(ai.Config)
 
-	flag.BoolVar(&config.Centre, "centre", false, "take the centre first (1v1)")
-	flag.BoolVar(&config.Conservative, "conservative", false, "no rushing")
-	flag.BoolVar(&config.DockOnly, "dockonly", false, "make initial dockings and stop")
-	flag.BoolVar(&config.ForceRush, "forcerush", false, "always rush")
-	flag.BoolVar(&config.Imperfect, "imperfect", false, "don't use \"perfect\" GA")
-	flag.BoolVar(&config.NoMsg, "nomsg", false, "no angle messages")
-	flag.BoolVar(&config.Profile, "profile", false, "run Golang CPU profile")
-	flag.BoolVar(&config.Split, "split", false, "split ships at start")
-	flag.BoolVar(&config.Timeseed, "timeseed", false, "seed RNG with time")
+	config_flags(flag.CommandLine, config)
 
-	flag.Float64Var(&nav.Ignore_Collision_Dist, "icd", 100, "ignore collision distance (nav)")
+	var session_file, rerun_file string
 
-	flag.IntVar(&config.TestGA, "testga", -1, "test GA on thus turn")
+	flag.StringVar(&session_file, "session", "", "record every frame and every order sent (%d is replaced by our pid)")
+	flag.StringVar(&rerun_file, "rerun", "", "re-run a recorded session offline, reporting where our orders first differ")
 
 	flag.Parse()
 
+	if rerun_file != "" {
+		os.Exit(rerun(rerun_file))
+	}
+
 	game := hal.NewGame()
+	game.Budget().SetLimit(time.Duration(config.BudgetMs) * time.Millisecond)
 
 	if config.Profile {
 		outfile, _ := os.Create("profile.prof")
@@ -70,6 +70,19 @@ func main() {
 	}()
 
 	game.StartLog(fmt.Sprintf("log%d.txt", game.Pid()))
+
+	if config.TraceFile != "" {
+		game.StartTrace(strings.Replace(config.TraceFile, "%d", fmt.Sprintf("%d", game.Pid()), -1))
+		defer game.StopTrace()							// Runs before the recover() above, as the game ends.
+	}
+
+	if session_file != "" {
+		err := game.StartSession(strings.Replace(session_file, "%d", fmt.Sprintf("%d", game.Pid()), -1), os.Args[1:])
+		if err != nil {
+			game.LogWithoutTurn("Not recording session: %v", err)
+		}
+	}
+
 	game.LogWithoutTurn("--------------------------------------------------------------------------------")
 	game.LogWithoutTurn("%s %s starting up at %s", NAME, VERSION, time.Now().Format("2006-01-02T15:04:05Z"))
 
@@ -90,7 +103,10 @@ func main() {
 	for {
 		start_time := time.Now()
 
-		game.Parse()
+		err := game.Parse()
+		if err != nil {
+			panic(fmt.Sprintf("Parse(): %v", err))		// The recover() above logs it and we quit; usually it's just the end of the game.
+		}
 
 		if config.Timeseed == false {
 			rand.Seed(int64(game.Turn() + game.Width() + game.Pid()))
@@ -104,12 +120,151 @@ func main() {
 			continue
 		}
 
-		overmind.Step()
+		overmind.StepSafely()								// On a panic, logs it and gives fallback orders.
 		game.Send(config.NoMsg)
 
+		if game.Budget().Expired() {
+			game.Log("Over budget: turn took %v (budget %v)", game.Budget().Elapsed(), game.Budget().Limit())
+		}
+
 		if time.Now().Sub(start_time) > longest_turn {
 			longest_turn = time.Now().Sub(start_time)
 			longest_turn_number = game.Turn()
 		}
 	}
 }
+
+// config_flags sets up the flags that configure the AI, for the real command line or (in rerun)
+// a recorded one.
+
+func config_flags(fs *flag.FlagSet, config *ai.Config) {
+
+	fs.BoolVar(&config.Centre, "centre", false, "take the centre first (1v1)")
+	fs.BoolVar(&config.Conservative, "conservative", false, "no rushing")
+	fs.BoolVar(&config.DockOnly, "dockonly", false, "make initial dockings and stop")
+	fs.BoolVar(&config.ForceRush, "forcerush", false, "always rush")
+	fs.BoolVar(&config.Hungarian, "hungarian", false, "optimal (min-cost) target assignment")
+	fs.BoolVar(&config.Imperfect, "imperfect", false, "don't use \"perfect\" GA")
+	fs.BoolVar(&config.NoMsg, "nomsg", false, "no angle messages")
+	fs.BoolVar(&config.Profile, "profile", false, "run Golang CPU profile")
+	fs.BoolVar(&config.Split, "split", false, "split ships at start")
+	fs.BoolVar(&config.Timeseed, "timeseed", false, "seed RNG with time")
+	fs.BoolVar(&config.GADeterministic, "gadeterministic", false, "parallel GA ignores the clock")
+
+	fs.Float64Var(&nav.Ignore_Collision_Dist, "icd", 100, "ignore collision distance (nav)")
+
+	fs.StringVar(&config.EnemyModels, "models", "", "enemy models for the rush GA (repeat,still,charge,flee,mirror,policy)")
+	fs.StringVar(&config.PolicyFile, "policy", "policy.json", "learnt policy file for the \"policy\" enemy model")
+	fs.StringVar(&config.ProblemsFile, "problems", "", "weights file for the target problem sources")
+	fs.StringVar(&config.ParamsFile, "params", "", "JSON file of tuned parameters")
+	fs.StringVar(&config.TraceFile, "trace", "", "write a JSON-lines debug trace per turn (%d is replaced by our pid)")
+
+	fs.IntVar(&config.BudgetMs, "budget", int(hal.DEFAULT_BUDGET / time.Millisecond), "time budget per turn in ms (0: unlimited)")
+	fs.IntVar(&config.GAWorkers, "gaworkers", 0, "goroutines for the rush GA (0: classic single-threaded GA)")
+	fs.IntVar(&config.Horizon, "horizon", 1, "turns of lookahead for the rush GA")
+	fs.IntVar(&config.TestGA, "testga", -1, "test GA on thus turn")
+}
+
+// rerun feeds a recorded session's frames through the Overmind, as the main loop does, and
+// compares our orders with the recorded ones. The AI is configured by the session's recorded
+// flags, not by any others given with -rerun. Returns the exit code: 2 if the orders differ,
+// 3 if Step() panicked.
+
+func rerun(filename string) int {
+
+	session, err := hal.LoadSession(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Session recorded with flags: %s\n", session.Args)
+
+	if flag.NFlag() > 1 {
+		fmt.Printf("Using those; other flags given with -rerun are ignored.\n")
+	}
+
+	config := new(ai.Config)
+
+	fs := flag.NewFlagSet("session", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	config_flags(fs, config)
+	fs.String("session", "", "")									// Recorded sessions always have this one.
+	fs.String("rerun", "", "")
+
+	err = fs.Parse(strings.Fields(session.Args))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: recorded flags: %v\n", filename, err)
+		return 1
+	}
+
+	game, err := hal.ReadGame(session.Reader(), ioutil.Discard)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: init: %v\n", filename, err)
+		return 1
+	}
+
+	game.Budget().SetLimit(time.Duration(config.BudgetMs) * time.Millisecond)
+
+	overmind := ai.NewOvermind(game, config)
+
+	for turn := 0; turn < len(session.Frames); turn++ {
+
+		err := game.Parse()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: frame %d: %v\n", filename, turn, err)
+			return 1
+		}
+
+		if config.Timeseed == false {
+			rand.Seed(int64(game.Turn() + game.Width() + game.Pid()))
+		}
+
+		if config.TestGA > -1 {
+			if game.Turn() == config.TestGA {
+				overmind.EnterGeneticAlgorithm()
+			}
+		} else if rerun_step(overmind) == false {
+			return 3
+		}
+
+		if turn >= len(session.Sent) {
+			break									// The recording ended (e.g. we were killed) before we replied.
+		}
+
+		game.ValidateOrders(false)					// As Send() does before sending (and recording) them.
+
+		recorded, err := hal.ParseOrders(session.Sent[turn])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: sent %d: %v\n", filename, turn, err)
+			return 1
+		}
+
+		if hal.SameOrders(game.SentOrders(config.NoMsg), recorded) == false {
+			fmt.Printf("Turn %d differs:\n    recorded: %s\n    now:      %s\n", turn, session.Sent[turn], game.RawOutput(true, config.NoMsg))
+			if game.Budget().Expired() {
+				fmt.Printf("(Turn %d went over budget, so the difference may just be timing.)\n", turn)
+			}
+			return 2
+		}
+	}
+
+	fmt.Printf("All %d turns matched.\n", hal.Min(len(session.Frames), len(session.Sent)))
+	return 0
+}
+
+// rerun_step calls Step() directly, rather than StepSafely() as the live game does, so that a
+// panic isn't hidden behind the fallback orders: it's reported with its stack instead.
+
+func rerun_step(overmind *ai.Overmind) (ok bool) {
+
+	defer func() {
+		if p := recover(); p != nil {
+			fmt.Printf("Turn %d: Step() panicked: %v\n%s", overmind.Game.Turn(), p, debug.Stack())
+			ok = false
+		}
+	}()
+
+	overmind.Step()
+	return true
+}
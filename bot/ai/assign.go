@@ -0,0 +1,165 @@
+package ai
+
+import (
+	"math"
+
+	hal "../core"
+	pil "../pilot"
+)
+
+// ChooseTargetsOptimally is the alternative to the greedy part of ChooseTargets(), used if
+// Config.Hungarian is set. Each problem is split into Need slots, and free pilots are matched
+// to slots so that the total of Dist / Value is as small as possible. As with the greedy
+// version, if there aren't enough slots to go round, the problems are used again. Returns false,
+// having assigned nothing, if it runs out of time (see HUNGARIAN_BUDGET); the caller should then
+// fall back to the greedy way.
+
+func (self *Overmind) ChooseTargetsOptimally() bool {
+
+	var pilots []*pil.Pilot
+
+	for _, pilot := range self.Pilots {
+
+		if pilot.DockedStatus != hal.UNDOCKED {
+			continue
+		}
+
+		if pilot.Target.Type() != hal.NOTHING {			// Because our target wasn't reset for some reason.
+			pilot.MessageWhileLocked()
+			continue
+		}
+
+		pilots = append(pilots, pilot)
+	}
+
+	if len(pilots) == 0 {
+		return true
+	}
+
+	var slots []*Problem
+
+	for len(slots) < len(pilots) {
+
+		problems := self.AllProblems()
+		if len(problems) == 0 {
+			return true
+		}
+
+		for _, problem := range problems {
+			for n := 0; n < problem.Need && n < len(pilots); n++ {
+				slots = append(slots, problem)
+			}
+		}
+	}
+
+	cost := make([][]float64, len(pilots))
+
+	for i, pilot := range pilots {
+		cost[i] = make([]float64, len(slots))
+		for j, problem := range slots {
+			cost[i][j] = pilot.Dist(problem.Entity) / problem.Value
+		}
+	}
+
+	assignment, ok := hungarian(cost, func() bool {
+		return self.Game.Budget().Used(HUNGARIAN_BUDGET)
+	})
+
+	if ok == false {
+		self.Game.Log("ChooseTargetsOptimally(): out of time with %d pilots and %d slots", len(pilots), len(slots))
+		return false
+	}
+
+	for i, j := range assignment {
+		pilots[i].Target = slots[j].Entity
+		pilots[i].Message = slots[j].Message
+	}
+
+	return true
+}
+
+// hungarian solves the assignment problem for an n x m cost matrix with n <= m, giving each
+// row a different column so the total cost is minimal. Returns the column of each row.
+// This is the usual O(n^2 m) version with potentials; internally rows and columns count from
+// 1, with column 0 as a dummy. give_up() is asked before each row is added, and if it says so
+// the result is nil, false.
+
+func hungarian(cost [][]float64, give_up func() bool) ([]int, bool) {
+
+	n := len(cost)
+	m := len(cost[0])
+
+	u := make([]float64, n + 1)
+	v := make([]float64, m + 1)
+	p := make([]int, m + 1)							// Column --> row assigned to it (0 if none)
+	way := make([]int, m + 1)
+
+	for i := 1; i <= n; i++ {
+
+		if give_up() {
+			return nil, false
+		}
+
+		p[0] = i
+		j0 := 0
+
+		minv := make([]float64, m + 1)
+		used := make([]bool, m + 1)
+
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+
+		for {
+			used[j0] = true
+			i0 := p[j0]
+			delta := math.Inf(1)
+			j1 := 0
+
+			for j := 1; j <= m; j++ {
+				if used[j] == false {
+					cur := cost[i0 - 1][j - 1] - u[i0] - v[j]
+					if cur < minv[j] {
+						minv[j] = cur
+						way[j] = j0
+					}
+					if minv[j] < delta {
+						delta = minv[j]
+						j1 = j
+					}
+				}
+			}
+
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+
+			j0 = j1
+
+			if p[j0] == 0 {
+				break
+			}
+		}
+
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	ret := make([]int, n)
+
+	for j := 1; j <= m; j++ {
+		if p[j] != 0 {
+			ret[p[j] - 1] = j - 1
+		}
+	}
+
+	return ret, true
+}
@@ -0,0 +1,167 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func never_give_up() bool {
+	return false
+}
+
+// assign_test_check fails the test unless assignment gives each row a different, valid column
+// at the given total cost.
+
+func assign_test_check(t *testing.T, cost [][]float64, assignment []int, want float64) {
+
+	if len(assignment) != len(cost) {
+		t.Fatalf("got %d columns for %d rows", len(assignment), len(cost))
+	}
+
+	used := make(map[int]bool)
+	total := 0.0
+
+	for i, j := range assignment {
+		if j < 0 || j >= len(cost[i]) {
+			t.Fatalf("row %d got column %d, out of range", i, j)
+		}
+		if used[j] {
+			t.Fatalf("column %d used twice in %v", j, assignment)
+		}
+		used[j] = true
+		total += cost[i][j]
+	}
+
+	if math.Abs(total - want) > 1e-9 {
+		t.Errorf("%v costs %v, want %v", assignment, total, want)
+	}
+}
+
+// assign_test_brute_force is the cheapest total over every way of giving rows distinct columns.
+
+func assign_test_brute_force(cost [][]float64, row int, used []bool) float64 {
+
+	if row == len(cost) {
+		return 0
+	}
+
+	best := math.Inf(1)
+
+	for j := range cost[row] {
+		if used[j] == false {
+			used[j] = true
+			best = math.Min(best, cost[row][j] + assign_test_brute_force(cost, row + 1, used))
+			used[j] = false
+		}
+	}
+
+	return best
+}
+
+func TestHungarianKnownOptimum(t *testing.T) {
+
+	// Greedy by row would take (0, 1) and (1, 0) for 1 + 2, leaving row 2 with column 2 for 9.
+	// The optimum is (0, 2), (1, 1), (2, 0): 3 + 3 + 1 = 7.
+
+	cost := [][]float64{
+		{4, 1, 3},
+		{2, 3, 6},
+		{1, 8, 9},
+	}
+
+	assignment, ok := hungarian(cost, never_give_up)
+	if ok == false {
+		t.Fatalf("gave up without being asked to")
+	}
+
+	assign_test_check(t, cost, assignment, 7)
+
+	want := []int{2, 1, 0}
+	for i := range want {
+		if assignment[i] != want[i] {
+			t.Errorf("got %v, want %v", assignment, want)
+			break
+		}
+	}
+}
+
+func TestHungarianRectangular(t *testing.T) {
+
+	// More columns than rows, as when there are more slots than pilots.
+
+	cost := [][]float64{
+		{7, 5, 9, 1.5},
+		{6, 2, 8, 1},
+	}
+
+	assignment, ok := hungarian(cost, never_give_up)
+	if ok == false {
+		t.Fatalf("gave up without being asked to")
+	}
+
+	assign_test_check(t, cost, assignment, 3.5)			// (0, 3) and (1, 1)
+
+	rng := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 200; n++ {
+
+		rows := 1 + rng.Intn(4)
+		cols := rows + rng.Intn(3)
+
+		cost := make([][]float64, rows)
+		for i := range cost {
+			cost[i] = make([]float64, cols)
+			for j := range cost[i] {
+				cost[i][j] = rng.Float64() * 100
+			}
+		}
+
+		assignment, _ := hungarian(cost, never_give_up)
+		assign_test_check(t, cost, assignment, assign_test_brute_force(cost, 0, make([]bool, cols)))
+	}
+}
+
+func TestHungarianDegenerate(t *testing.T) {
+
+	// A single cell.
+
+	assignment, _ := hungarian([][]float64{{3}}, never_give_up)
+	assign_test_check(t, [][]float64{{3}}, assignment, 3)
+
+	// Every cost the same (as when several slots belong to one problem): any assignment will do,
+	// but the columns must still differ.
+
+	same := [][]float64{
+		{2, 2, 2},
+		{2, 2, 2},
+		{2, 2, 2},
+	}
+
+	assignment, _ = hungarian(same, never_give_up)
+	assign_test_check(t, same, assignment, 6)
+
+	// Zeros and repeated columns.
+
+	repeated := [][]float64{
+		{0, 0, 5},
+		{0, 0, 5},
+	}
+
+	assignment, _ = hungarian(repeated, never_give_up)
+	assign_test_check(t, repeated, assignment, 0)
+}
+
+func TestHungarianGivesUp(t *testing.T) {
+
+	cost := [][]float64{
+		{1, 2},
+		{2, 1},
+	}
+
+	assignment, ok := hungarian(cost, func() bool { return true })
+
+	if ok || assignment != nil {
+		t.Errorf("got %v, %v after giving up; want nil, false", assignment, ok)
+	}
+}
@@ -55,3 +55,27 @@ func (self *Overmind) SetCowardFlag() {
 		self.CowardFlag = true
 	}
 }
+
+// --------------------------------------------
+
+// The coward strategy: once we're hopelessly outnumbered in a 4 player game, run and hide.
+
+type CowardStrategy struct {
+	StrategyBase
+}
+
+func init() {
+	RegisterStrategy(COWARD_PRIORITY, new(CowardStrategy))
+}
+
+func (self *CowardStrategy) Name() string {
+	return "coward"
+}
+
+func (self *CowardStrategy) Wants(o *Overmind) bool {
+	return o.CowardFlag
+}
+
+func (self *CowardStrategy) Execute(o *Overmind) {
+	o.CowardStep()
+}
@@ -242,6 +242,8 @@ func (self *Overmind) MakeDefaultDockChoice() {
 		}
 	}
 
+	self.OpeningDocks = docks
+
 	if len(docks) < 3 {
 		return
 	}
@@ -326,6 +328,8 @@ func (self *Overmind) ChooseCentreDocks() {
 		docks = append(docks, hal.OpeningDockHelper(3, planet, my_cog)...)
 	}
 
+	self.OpeningDocks = docks
+
 	if len(docks) < 3 {
 		return
 	}
@@ -487,5 +491,71 @@ func (self *Overmind) EnterGeneticAlgorithm() {
 		}
 	}
 
-	gen.EvolveRush(self.Game, self.RushEnemyID, play_perfect)
+	rush_config := gen.RushConfig{
+		Horizon: self.Config.Horizon,
+		Workers: self.Config.GAWorkers,
+		Deterministic: self.Config.GADeterministic,
+	}
+
+	rush_config.Tracker = self.EnemyModels				// If nil, the GA keeps its old fixed scenarios.
+
+	if rush_config.Workers > 0 {
+		rush_config.Seed = rand.Int63()			// MyBot seeds the global RNG each turn, so this is repeatable too.
+	}
+
+	gen.EvolveRush(self.Game, self.RushEnemyID, play_perfect, rush_config)
+}
+
+// --------------------------------------------
+
+// The opening strategy only plays turn 0 of a rush, when our ships cluster up.
+
+type OpeningStrategy struct {
+	StrategyBase
+}
+
+// The rush fight strategy hands the turn to the GA once our rush has met the enemy. If it
+// can avoid a bad 2v1 instead, it sets that up and lets the normal strategy play the turn.
+
+type RushFightStrategy struct {
+	StrategyBase
+}
+
+func init() {
+	RegisterStrategy(OPENING_PRIORITY, new(OpeningStrategy))
+	RegisterStrategy(RUSH_FIGHT_PRIORITY, new(RushFightStrategy))
+}
+
+func (self *OpeningStrategy) Name() string {
+	return "opening"
+}
+
+func (self *OpeningStrategy) Wants(o *Overmind) bool {
+	return o.Game.Turn() == 0 && o.RushChoice == RUSHING
+}
+
+func (self *OpeningStrategy) Execute(o *Overmind) {
+	o.TurnZeroCluster()		// For tactical reasons - helps destroy single enemy ship sent to centre before it collides with us.
+}
+
+func (self *RushFightStrategy) Name() string {
+	return "rush fight"
+}
+
+func (self *RushFightStrategy) Wants(o *Overmind) bool {
+
+	if o.NeverGA || o.RushChoice != RUSHING || o.DetectRushFight() == false {
+		return false
+	}
+
+	if o.CanAvoidBad2v1() {
+		o.AvoidBad2v1()
+		return false
+	}
+
+	return true
+}
+
+func (self *RushFightStrategy) Execute(o *Overmind) {
+	o.EnterGeneticAlgorithm()
 }
@@ -0,0 +1,261 @@
+package ai
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+
+	hal "../core"
+	nav "../navigation"
+	pil "../pilot"
+)
+
+// If Step() panics, the live game shouldn't end for us. StepSafely() logs the panic and its
+// stack, throws away whatever orders Step() had given, and uses the fallback planner instead.
+// The planner is the basic bot's AI (see basic/ai/basic_ai.go): it looks only at the Game,
+// not at our pilots or strategies, since those are presumably what's broken.
+
+// StepSafely is Step() for the live game. It returns false if Step() panicked.
+
+func (self *Overmind) StepSafely() (ok bool) {
+
+	if self.Panicked {
+		self.ResyncPilots()
+		self.Panicked = false
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			self.Game.Log("Step() panicked: %v\n%s", p, debug.Stack())
+			self.Panicked = true
+			self.Game.ClearOrders()
+			err := Fallback(self.Game)
+			if err != nil {
+				self.Game.Log("%v", err)
+				self.Game.ClearOrders()
+			}
+			ok = false
+		}
+	}()
+
+	self.Step()
+	return true
+}
+
+// ResyncPilots gives a pilot to any of our ships without one. ResetPilots() only makes pilots for
+// ships born this turn, so if it died part way through, some ships would never get one.
+
+func (self *Overmind) ResyncPilots() {
+
+	have_pilot := make(map[int]bool)
+	for _, pilot := range self.Pilots {
+		have_pilot[pilot.Id] = true
+	}
+
+	for _, ship := range self.Game.MyShips() {
+		if have_pilot[ship.Id] == false && ship.Birth != self.Game.Turn() {		// Ships born this turn are added by ResetPilots()
+			self.Game.Log("ResyncPilots(): ship %d had no pilot", ship.Id)
+			self.Pilots = append(self.Pilots, pil.NewPilot(ship.Id, self.Game))
+		}
+	}
+}
+
+// --------------------------------------------
+
+// Fallback gives orders to all our ships, as the basic bot would. It shouldn't panic,
+// but if it does the error says so, and the orders should be thrown away.
+
+func Fallback(game *hal.Game) (err error) {
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("Fallback() panicked: %v\n%s", p, debug.Stack())
+		}
+	}()
+
+	targets := fallback_targets(game)
+	fallback_moves(game, targets)
+	fallback_anti_collision(game)
+
+	return nil
+}
+
+type fallback_problem struct {
+	entity				hal.Entity
+	value				float64
+	need				int
+}
+
+// The nav functions want somewhere to put their debug info; we don't care about it.
+
+type fallback_navstacker struct {
+	game				*hal.Game
+}
+
+func (self *fallback_navstacker) AddToNavStack(format_string string, args ...interface{}) {}
+func (self *fallback_navstacker) AddWaypoint(x, y float64) {}
+func (self *fallback_navstacker) GetGame() *hal.Game { return self.game }
+
+func fallback_problems(game *hal.Game) []*fallback_problem {
+
+	var ret []*fallback_problem
+
+	for _, planet := range game.AllPlanets() {
+
+		enemies := game.EnemiesNearPlanet(planet)
+
+		if len(enemies) == 0 {
+			if game.DesiredSpots(planet) > 0 {
+				value := 1.0 / 1.4; if game.InitialPlayers() > 2 { value = 1.0 }
+				ret = append(ret, &fallback_problem{planet, value, game.DesiredSpots(planet)})
+			}
+			continue
+		}
+
+		for _, enemy := range enemies {
+			ret = append(ret, &fallback_problem{enemy, 1.0, 2})
+		}
+	}
+
+	for _, ship := range game.EnemyShips() {
+		ret = append(ret, &fallback_problem{ship, 1.0, 1})
+	}
+
+	return ret
+}
+
+// fallback_targets is the basic bot's ChooseTargets(): each mobile ship greedily takes the
+// problem with the best distance / value. Returns ship ID --> target.
+
+func fallback_targets(game *hal.Game) map[int]hal.Entity {
+
+	ret := make(map[int]hal.Entity)
+
+	all_problems := fallback_problems(game)
+
+	for _, ship := range game.MyShips() {
+
+		if len(all_problems) == 0 {
+			all_problems = fallback_problems(game)
+			if len(all_problems) == 0 {
+				break
+			}
+		}
+
+		if ship.CanMove() == false {
+			continue
+		}
+
+		sort.Slice(all_problems, func(a, b int) bool {
+			return ship.Dist(all_problems[a].entity) / all_problems[a].value <
+			       ship.Dist(all_problems[b].entity) / all_problems[b].value
+		})
+
+		ret[ship.Id] = all_problems[0].entity
+
+		all_problems[0].need--
+		if all_problems[0].need <= 0 {
+			all_problems = all_problems[1:]
+		}
+	}
+
+	return ret
+}
+
+func fallback_moves(game *hal.Game, targets map[int]hal.Entity) {
+
+	avoid_list := game.AllImmobile()
+	ns := &fallback_navstacker{game}
+
+	for _, ship := range game.MyShips() {
+
+		target, ok := targets[ship.Id]
+		if ok == false || ship.CanMove() == false {
+			continue
+		}
+
+		side := nav.DecideSideFromTarget(ship, target, game, ns)
+
+		switch target.Type() {
+
+		case hal.PLANET:
+
+			planet := target.(*hal.Planet)
+
+			if ship.CanDock(planet) {
+				game.Dock(ship, *planet)
+				continue
+			}
+
+			speed, degrees, _ := nav.GetApproach(ship, planet, hal.DOCKING_RADIUS + hal.SHIP_RADIUS - 0.001, avoid_list, side, ns)
+			game.Thrust(ship, speed, degrees)
+
+		case hal.SHIP:
+
+			speed, degrees, _ := nav.GetApproach(ship, target, 5.45, avoid_list, side, ns)
+			game.Thrust(ship, speed, degrees)
+		}
+	}
+}
+
+// fallback_anti_collision is the basic bot's AntiCollision() and HaltUnsafeOrders(): since the
+// moves avoided all immobile things, only our mobile ships can hit each other. Moves are accepted
+// one at a time if they're safe given those already accepted, slowing down after a few passes.
+// Whatever is left is stopped.
+
+func fallback_anti_collision(game *hal.Game) {
+
+	var mobile_ships []*hal.Ship
+
+	for _, ship := range game.MyShips() {
+		if ship.CanMove() {
+			mobile_ships = append(mobile_ships, ship)		// This does include ships that will dock or stay still.
+		}
+	}
+
+	validated := make(map[int]bool)
+
+	for n := 0; n < 11 && len(validated) < len(mobile_ships); n++ {
+
+		Ship1Loop:
+
+		for _, ship1 := range mobile_ships {
+
+			if validated[ship1.Id] {
+				continue
+			}
+
+			speed1, angle1 := game.CurrentOrder(ship1).Course()
+
+			if n >= 5 && speed1 > 0 {
+				speed1--
+				game.Thrust(ship1, speed1, angle1)
+			}
+
+			for _, ship2 := range mobile_ships {
+
+				if ship2 == ship1 || ship2.Dist(ship1) > 15 {
+					continue
+				}
+
+				speed2, angle2 := 0, 0
+
+				if validated[ship2.Id] {
+					speed2, angle2 = game.CurrentOrder(ship2).Course()
+				}
+
+				if hal.ShipsWillCollide(ship1, speed1, angle1, -1, ship2, speed2, angle2, -1) {
+					continue Ship1Loop
+				}
+			}
+
+			validated[ship1.Id] = true
+		}
+	}
+
+	for _, ship := range mobile_ships {
+		if validated[ship.Id] == false {
+			game.ClearOrder(ship)
+		}
+	}
+}
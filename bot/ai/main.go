@@ -3,9 +3,11 @@ package ai
 import (
 	"math/rand"
 	"sort"
+	"strings"
 	// "time"
 
 	hal "../core"
+	gen "../genetic"
 	pil "../pilot"
 )
 
@@ -20,6 +22,11 @@ const (
 	DEBUG_SHIP_ID = -1
 )
 
+const (
+	OPTIMISE_BUDGET = 0.5		// Fraction of the turn's Budget after which OptimisePilots() gives up, leaving time for navigation.
+	HUNGARIAN_BUDGET = 0.25		// Fraction of the turn's Budget after which ChooseTargetsOptimally() gives up for the greedy way.
+)
+
 // --------------------------------------------
 
 type Config struct {
@@ -27,12 +34,22 @@ type Config struct {
 	Conservative			bool
 	DockOnly				bool
 	ForceRush				bool
+	Hungarian				bool				// Optimal (min-cost) target assignment instead of greedy
 	NoMsg					bool
 	Imperfect				bool
 	Profile					bool
 	Split					bool
 	Timeseed				bool
 
+	Horizon					int					// Turns the rush GA looks ahead (0 is treated as 1)
+	EnemyModels				string				// Comma separated; "" means the defaults
+	PolicyFile				string				// Learnt policy for the "policy" enemy model
+	ProblemsFile			string				// Weights for the problem sources; "" means the defaults
+	ParamsFile				string				// Tuned numbers (hal.Params); "" means the defaults
+	TraceFile				string				// JSON-lines trace of every turn (see trace.go); "" means none
+	BudgetMs				int					// Per-turn time budget in ms (MyBot sets it on the game; see hal.Budget)
+	GAWorkers				int					// Goroutines for the rush GA; 0 means the classic single-threaded GA
+	GADeterministic			bool				// Parallel GA ignores the clock, so results don't depend on speed
 	TestGA					int
 }
 
@@ -50,6 +67,16 @@ type Overmind struct {
 
 	RushEnemiesTouched		map[int]bool		// For deciding whether we can enter GA.
 	EverDocked				bool				// Also allows us to enter the GA.
+
+	EnemyModels				*gen.ModelTracker	// What the GA thinks the enemy might do, and how well each guess has done. Nil if unused.
+
+	ProblemWeights			map[string]ProblemWeight	// Source name --> weight, see problems.go
+
+	Strategy				Strategy			// Whichever strategy played the last turn (see strategy.go).
+
+	OpeningDocks			[]*hal.Port			// Candidate docks from the opening's OpeningDockHelper() calls, for debug drawing.
+
+	Panicked				bool				// Step() panicked last turn (see fallback.go).
 }
 
 func NewOvermind(game *hal.Game, config *Config) *Overmind {
@@ -57,7 +84,15 @@ func NewOvermind(game *hal.Game, config *Config) *Overmind {
 	ret.Game = game
 	ret.Config = config
 
-	game.SetThreatRange(20)						// This value seems to be surprisingly fine-tuned.
+	if config.ParamsFile != "" {
+		params, err := hal.LoadParams(config.ParamsFile)
+		if err != nil {
+			game.Log("Params not used: %v", err)
+		}
+		game.SetParams(params)						// On error, this is the defaults.
+	}
+
+	game.SetThreatRange(game.Params().ThreatRange)
 
 	ret.FindRushEnemy()
 
@@ -71,13 +106,72 @@ func NewOvermind(game *hal.Game, config *Config) *Overmind {
 	ret.FirstLaunchTurn = -1
 	ret.RushEnemiesTouched = make(map[int]bool)
 
+	if config.EnemyModels != "" && ret.NeverGA == false {		// Otherwise the GA keeps its old fixed scenarios, so needs no tracking.
+		ret.EnemyModels = gen.NewModelTracker(ret.MakeEnemyModels(), 5)
+	}
+
+	ret.ProblemWeights = ret.MakeProblemWeights()
+
 	return ret
 }
 
+func (self *Overmind) MakeEnemyModels() []gen.EnemyModel {
+
+	if self.Config.EnemyModels == "" {
+		return gen.DefaultEnemyModels()
+	}
+
+	var models []gen.EnemyModel
+
+	for _, name := range strings.Split(self.Config.EnemyModels, ",") {
+
+		var model gen.EnemyModel
+		var err error
+
+		if name == "policy" {
+			model, err = gen.LoadPolicyModel(self.Config.PolicyFile)
+		} else {
+			model, err = gen.NewEnemyModel(name)
+		}
+
+		if err != nil {
+			self.Game.Log("Enemy model \"%s\" not used: %v", name, err)
+			continue
+		}
+
+		models = append(models, model)
+	}
+
+	if len(models) == 0 {
+		return gen.DefaultEnemyModels()
+	}
+
+	return models
+}
+
+func (self *Overmind) MakeProblemWeights() map[string]ProblemWeight {
+
+	if self.Config.ProblemsFile == "" {
+		return DefaultProblemWeights()
+	}
+
+	weights, err := LoadProblemWeights(self.Config.ProblemsFile)
+	if err != nil {
+		self.Game.Log("Problem weights not used: %v", err)
+		return DefaultProblemWeights()
+	}
+
+	return weights
+}
+
 // --------------------------------------------
 
 func (self *Overmind) Step() {
 
+	if self.EnemyModels != nil && self.NeverGA == false {
+		self.EnemyModels.Update(self.Game)
+	}
+
 	if self.EverDocked == false {
 		for _, ship := range self.Game.MyShips() {
 			if ship.DockedStatus != hal.UNDOCKED {
@@ -118,49 +212,13 @@ func (self *Overmind) Step() {
 
 	self.SetCowardFlag()
 
-	if self.Game.Turn() == 0 {
-		if self.RushChoice != RUSHING {
-			self.ChooseThreeDocks()
-		} else {
-			self.TurnZeroCluster()		// For tactical reasons - helps destroy single enemy ship sent to centre before it collides with us.
-			return
-		}
-	}
-
-	if self.CowardFlag {
-		self.CowardStep()
-		return
-	}
-
-	if self.NeverGA == false && self.RushChoice == RUSHING && self.DetectRushFight() {
-		if self.CanAvoidBad2v1() {
-			self.AvoidBad2v1()
-		} else {
-			self.EnterGeneticAlgorithm()
-			return
-		}
+	if self.Game.Turn() == 0 && self.RushChoice != RUSHING {
+		self.ChooseThreeDocks()
 	}
 
-	self.NormalStep()
+	self.RunStrategy(self.ChooseStrategy())
 
-	self.DebugNavStack()
-	self.DebugInhibition()
-	self.DebugOrders()
-	self.DebugTargets()
-}
-
-func (self *Overmind) NormalStep() {
-
-	if self.Config.DockOnly == false {
-		self.ChooseTargets()
-	}
-	self.OptimisePilots()
-	self.DetectDanger()					// We might use target info for this in future, so put it here.
-	self.ExecuteMoves()
-
-	if self.RushChoice == RUSHING && self.AvoidingBad2v1 == false {
-		self.UndockAll()
-	}
+	self.Trace()
 }
 
 // --------------------------------------------
@@ -297,7 +355,7 @@ func (self *Overmind) ExecuteMoves() {
 
 	for _, pilot := range mobile_pilots {
 		if pilot.HasExecuted == false {
-			if pilot.Plan != "" {
+			if pilot.Plan.IsNone() == false {
 				pilot.PlanThrust(0, 0)
 				// pilot.Message = pil.MSG_ATC_RESTRICT
 				pilot.ExecutePlan()
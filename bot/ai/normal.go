@@ -0,0 +1,65 @@
+package ai
+
+// The normal strategy: choose targets, optimise who goes where, and move. It always wants the
+// turn, so it's the fallback when no other strategy does.
+
+type NormalStrategy struct {
+	StrategyBase
+}
+
+func init() {
+	RegisterStrategy(NORMAL_PRIORITY, new(NormalStrategy))
+	RegisterStrategy(DOCK_ONLY_PRIORITY, new(DockOnlyStrategy))
+}
+
+func (self *NormalStrategy) Name() string {
+	return "normal"
+}
+
+func (self *NormalStrategy) Wants(o *Overmind) bool {
+	return true
+}
+
+func (self *NormalStrategy) Assign(o *Overmind) {
+	o.ChooseTargets()
+	o.OptimisePilots()
+}
+
+func (self *NormalStrategy) Plan(o *Overmind) {
+	o.DetectDanger()					// We might use target info for this in future, so put it here.
+}
+
+func (self *NormalStrategy) Execute(o *Overmind) {
+
+	o.ExecuteMoves()
+
+	if o.RushChoice == RUSHING && o.AvoidingBad2v1 == false {
+		o.UndockAll()
+	}
+
+	o.DebugNavStack()
+	o.DebugInhibition()
+	o.DebugOrders()
+	o.DebugTargets()
+}
+
+// --------------------------------------------
+
+// The dock-only strategy is the normal one without ChooseTargets(), so pilots keep whatever
+// targets they already have (i.e. the docks chosen on turn 0).
+
+type DockOnlyStrategy struct {
+	NormalStrategy
+}
+
+func (self *DockOnlyStrategy) Name() string {
+	return "dock-only"
+}
+
+func (self *DockOnlyStrategy) Wants(o *Overmind) bool {
+	return o.Config.DockOnly
+}
+
+func (self *DockOnlyStrategy) Assign(o *Overmind) {
+	o.OptimisePilots()
+}
@@ -0,0 +1,294 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	hal "../core"
+	pil "../pilot"
+)
+
+// A ProblemSource is something that makes Problems for ChooseTargets() to hand out. Sources
+// are asked once per planet (in planet order) and then once with a nil planet, so sources
+// about planets should only answer the former, and others only the latter. This keeps the
+// problems grouped by planet, as they always were.
+//
+// Sources are either for rush play (RushChoice is RUSHING and we're not avoiding a bad 2v1)
+// or for normal play, never both. A source can also be a fallback for another, asked only
+// when that one has no problems to give (see RegisterFallbackSource).
+//
+// Each source's problems are adjusted by its ProblemWeight, which can be set from a JSON file
+// keyed by source name, e.g.
+//
+//     {"colonise": {"value": 1.2}, "contest": {"need": 1}, "harass": {"off": true}}
+
+type ProblemSource interface {
+	Name()														string
+	Rush()														bool
+	Problems(o *Overmind, planet *hal.Planet)					[]*Problem
+}
+
+type ProblemWeight struct {
+	Value					float64				`json:"value"`		// Multiplies each problem's Value
+	Need					int					`json:"need"`		// Added to each problem's Need
+	Off						bool				`json:"off"`
+}
+
+type registered_source struct {
+	source					ProblemSource
+	fallback_for			string				// Name of the source this one stands in for, or ""
+}
+
+var problem_sources []registered_source
+
+// RegisterProblemSource adds a source. Problems for any one planet (or for no planet) come out
+// in the order their sources were registered.
+
+func RegisterProblemSource(source ProblemSource) {
+	problem_sources = append(problem_sources, registered_source{source, ""})
+}
+
+// RegisterFallbackSource adds a source that's only asked when the named one, which must already
+// be registered, gave no problems (after its weight) for the same planet.
+
+func RegisterFallbackSource(source ProblemSource, primary string) {
+	for _, reg := range problem_sources {
+		if reg.source.Name() == primary {
+			problem_sources = append(problem_sources, registered_source{source, primary})
+			return
+		}
+	}
+	panic(fmt.Sprintf("RegisterFallbackSource(): no source \"%s\"", primary))
+}
+
+func DefaultProblemWeights() map[string]ProblemWeight {
+	ret := make(map[string]ProblemWeight)
+	for _, reg := range problem_sources {
+		ret[reg.source.Name()] = ProblemWeight{Value: 1}
+	}
+	return ret
+}
+
+// LoadProblemWeights reads weights from a file. Sources (or fields) not in the file keep
+// their defaults.
+
+func LoadProblemWeights(filename string) (map[string]ProblemWeight, error) {
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+
+	err = json.Unmarshal(b, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	ret := DefaultProblemWeights()
+
+	for name, msg := range raw {
+
+		weight, ok := ret[name]
+		if ok == false {
+			return nil, fmt.Errorf("%s: unknown problem source \"%s\"", filename, name)
+		}
+
+		err = json.Unmarshal(msg, &weight)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", filename, name, err)
+		}
+
+		ret[name] = weight
+	}
+
+	return ret, nil
+}
+
+// --------------------------------------------
+
+func init() {
+	RegisterProblemSource(new(ColoniseSource))
+	RegisterProblemSource(new(ContestSource))
+	RegisterProblemSource(new(HarassSource))
+	RegisterProblemSource(new(EscortSource))
+	RegisterFallbackSource(new(AttackDockedSource), "escort")
+}
+
+// ColoniseSource: planets with no enemies near them and room for more of our ships.
+
+type ColoniseSource struct {}
+
+func (self *ColoniseSource) Name() string { return "colonise" }
+func (self *ColoniseSource) Rush() bool { return false }
+
+func (self *ColoniseSource) Problems(o *Overmind, planet *hal.Planet) []*Problem {
+
+	if planet == nil || len(o.Game.EnemiesNearPlanet(planet)) > 0 {
+		return nil
+	}
+
+	capture_strength := o.Game.DesiredSpots(planet)
+
+	if capture_strength <= 0 {
+		return nil
+	}
+
+	value := 1.0 / 1.4; if o.Game.InitialPlayers() > 2 { value = 1.0 }
+
+	return []*Problem{&Problem{
+		Entity: planet,
+		Value: value,
+		Need: capture_strength,
+		Message: planet.Id,
+	}}
+}
+
+// ContestSource: enemies near a planet, whoever owns it. They might be threatening our docked
+// ships, in the way of our docking, or docked there themselves.
+
+type ContestSource struct {}
+
+func (self *ContestSource) Name() string { return "contest" }
+func (self *ContestSource) Rush() bool { return false }
+
+func (self *ContestSource) Problems(o *Overmind, planet *hal.Planet) []*Problem {
+
+	if planet == nil {
+		return nil
+	}
+
+	var ret []*Problem
+
+	for _, enemy := range o.Game.EnemiesNearPlanet(planet) {
+
+		// We can't skip Doomed targets here because we need to actually doom them before we dock.
+
+		ret = append(ret, &Problem{
+			Entity: enemy,
+			Value: 1.0,
+			Need: 2,
+			Message: planet.Id,
+		})
+	}
+
+	return ret
+}
+
+// HarassSource: every enemy ship, as an assassination target.
+
+type HarassSource struct {}
+
+func (self *HarassSource) Name() string { return "harass" }
+func (self *HarassSource) Rush() bool { return false }
+
+func (self *HarassSource) Problems(o *Overmind, planet *hal.Planet) []*Problem {
+
+	if planet != nil {
+		return nil
+	}
+
+	var ret []*Problem
+
+	for _, ship := range o.Game.EnemyShips() {
+
+		if ship.Doomed == false {		// Skip the ship (as an assassination target) if we expect it to die at time 0.
+			ret = append(ret, &Problem{	// Note that we may end up targetting it as a planet's secondary target.
+				Entity: ship,
+				Value: 1.0,
+				Need: 1,
+				Message: pil.MSG_ASSASSINATE,
+			})
+		}
+	}
+
+	return ret
+}
+
+// EscortSource (rush): our docked ships that aren't doomed yet.
+
+type EscortSource struct {}
+
+func (self *EscortSource) Name() string { return "escort" }
+func (self *EscortSource) Rush() bool { return true }
+
+func (self *EscortSource) Problems(o *Overmind, planet *hal.Planet) []*Problem {
+
+	if planet != nil {
+		return nil
+	}
+
+	var ret []*Problem
+
+	for _, ship := range o.HelpableDockedShips() {
+		ret = append(ret, &Problem{
+			Entity: ship,
+			Value: 1.0,
+			Need: 1,
+			Message: ship.Id,
+		})
+	}
+
+	return ret
+}
+
+// AttackDockedSource (rush): the rush enemy's docked ships, or all its ships if none are
+// docked. It's registered as the fallback for "escort", so it's only used while we have no
+// docked ships to escort.
+
+type AttackDockedSource struct {}
+
+func (self *AttackDockedSource) Name() string { return "attack docked" }
+func (self *AttackDockedSource) Rush() bool { return true }
+
+func (self *AttackDockedSource) Problems(o *Overmind, planet *hal.Planet) []*Problem {
+
+	if planet != nil {
+		return nil
+	}
+
+	var ret []*Problem
+
+	relevant_enemies := o.Game.ShipsOwnedBy(o.RushEnemyID)
+
+	some_are_docked := false
+
+	for _, ship := range relevant_enemies {
+		if ship.DockedStatus != hal.UNDOCKED {
+			some_are_docked = true
+			break
+		}
+	}
+
+	for _, ship := range relevant_enemies {
+
+		if ship.DockedStatus != hal.UNDOCKED || some_are_docked == false {
+
+			if ship.Doomed == false {
+				ret = append(ret, &Problem{
+					Entity: ship,
+					Value: 1.0,
+					Need: 1,
+					Message: ship.Id,
+				})
+			}
+		}
+	}
+
+	return ret
+}
+
+func (self *Overmind) HelpableDockedShips() []*hal.Ship {
+
+	var ret []*hal.Ship
+
+	for _, ship := range self.Game.MyShips() {
+		if ship.DockedStatus != hal.UNDOCKED && ship.Doomed == false {
+			ret = append(ret, ship)
+		}
+	}
+
+	return ret
+}
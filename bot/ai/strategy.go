@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"sort"
+)
+
+// A Strategy is one way of playing a turn. After the bookkeeping at the start of Step(), the
+// registered strategies are asked in priority order whether they want the turn; the first one
+// that does is run, phase by phase: Analyse, Assign, Plan, Execute.
+//
+// Wants() is allowed to change the Overmind (e.g. the rush fight strategy may decide to avoid
+// a bad 2v1 instead, which later strategies then see) so strategies are only asked until one
+// accepts. Something should always accept; the normal strategy does.
+//
+// New strategies register themselves from an init() function with RegisterStrategy().
+
+type Strategy interface {
+	Name()					string
+	Wants(o *Overmind)		bool
+	Analyse(o *Overmind)					// Look at the situation
+	Assign(o *Overmind)						// Give pilots targets
+	Plan(o *Overmind)						// Decide how the pilots get there
+	Execute(o *Overmind)					// Send the orders
+}
+
+// StrategyBase has empty phases, to be embedded by strategies that don't need them all.
+
+type StrategyBase struct {}
+
+func (self *StrategyBase) Analyse(o *Overmind) {}
+func (self *StrategyBase) Assign(o *Overmind) {}
+func (self *StrategyBase) Plan(o *Overmind) {}
+func (self *StrategyBase) Execute(o *Overmind) {}
+
+// --------------------------------------------
+
+const (
+	OPENING_PRIORITY = 10
+	COWARD_PRIORITY = 20
+	RUSH_FIGHT_PRIORITY = 30
+	DOCK_ONLY_PRIORITY = 40
+	NORMAL_PRIORITY = 1000
+)
+
+type registered_strategy struct {
+	priority				int
+	strategy				Strategy
+}
+
+var strategy_registry []registered_strategy
+
+// RegisterStrategy adds a strategy to those asked each turn. Lower priorities are asked first;
+// strategies with equal priority are asked in the order they were registered.
+
+func RegisterStrategy(priority int, strategy Strategy) {
+
+	strategy_registry = append(strategy_registry, registered_strategy{priority, strategy})
+
+	sort.SliceStable(strategy_registry, func(a, b int) bool {
+		return strategy_registry[a].priority < strategy_registry[b].priority
+	})
+}
+
+func Strategies() []Strategy {
+	var ret []Strategy
+	for _, r := range strategy_registry {
+		ret = append(ret, r.strategy)
+	}
+	return ret
+}
+
+// --------------------------------------------
+
+func (self *Overmind) ChooseStrategy() Strategy {
+	for _, r := range strategy_registry {
+		if r.strategy.Wants(self) {
+			return r.strategy
+		}
+	}
+	return nil
+}
+
+func (self *Overmind) RunStrategy(strategy Strategy) {
+
+	if strategy != self.Strategy {
+		self.Game.Log("Strategy: %s", strategy.Name())
+		self.Strategy = strategy
+	}
+
+	strategy.Analyse(self)
+	strategy.Assign(self)
+	strategy.Plan(self)
+	strategy.Execute(self)
+}
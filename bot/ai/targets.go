@@ -5,7 +5,6 @@ import (
 	"sort"
 
 	hal "../core"
-	pil "../pilot"
 )
 
 type Problem struct {
@@ -23,6 +22,12 @@ func (self *Problem) String() string {
 
 func (self *Overmind) ChooseTargets() {
 
+	if self.Config.Hungarian {
+		if self.ChooseTargetsOptimally() {			// See assign.go
+			return
+		}
+	}
+
 	all_problems := self.AllProblems()
 
 	// Initial assignment of problems to pilots...
@@ -66,132 +71,59 @@ func (self *Overmind) ChooseTargets() {
 
 func (self *Overmind) AllProblems() []*Problem {
 
-	if self.RushChoice == RUSHING && self.AvoidingBad2v1 == false {
-		return self.RushProblems()
-	}
+	// See problems.go for the sources.
+
+	rush := self.RushChoice == RUSHING && self.AvoidingBad2v1 == false
 
 	var all_problems []*Problem
 
 	for _, planet := range self.Game.AllPlanets() {
-		problems := self.PlanetProblems(planet)
-		all_problems = append(all_problems, problems...)
+		all_problems = append(all_problems, self.SourceProblems(rush, planet)...)
 	}
 
-	for _, ship := range self.Game.EnemyShips() {
-
-		if ship.Doomed == false {		// Skip the ship (as an assassination target) if we expect it to die at time 0.
-			problem := &Problem{		// Note that we may end up targetting it as a planet's secondary target.
-				Entity: ship,
-				Value: 1.0,
-				Need: 1,
-				Message: pil.MSG_ASSASSINATE,
-			}
-			all_problems = append(all_problems, problem)
-		}
-	}
+	all_problems = append(all_problems, self.SourceProblems(rush, nil)...)
 
 	return all_problems
 }
 
-func (self *Overmind) PlanetProblems(planet *hal.Planet) []*Problem {
+func (self *Overmind) SourceProblems(rush bool, planet *hal.Planet) []*Problem {
 
 	var ret []*Problem
 
-	enemies := self.Game.EnemiesNearPlanet(planet)
-	capture_strength := self.Game.DesiredSpots(planet)
-
-	switch len(enemies) {
-
-	case 0:
+	produced := make(map[string]bool)			// Source name --> whether it gave any problems
 
-		if capture_strength > 0 {
+	for _, reg := range problem_sources {
 
-			value := 1.0 / 1.4; if self.Game.InitialPlayers() > 2 { value = 1.0 }
+		source := reg.source
 
-			ret = append(ret, &Problem{
-				Entity: planet,
-				Value: value,
-				Need: capture_strength,
-				Message: planet.Id,
-			})
-		}
-
-	default:
-
-		for _, enemy := range enemies {
-
-			// We can't skip Doomed targets here because we need to actually doom them before we dock.
-
-			ret = append(ret, &Problem{
-				Entity: enemy,
-				Value: 1.0,
-				Need: 2,
-				Message: planet.Id,
-			})
+		if source.Rush() != rush {
+			continue
 		}
-	}
-
-	return ret
-}
-
-func (self *Overmind) RushProblems() []*Problem {
 
-	var problems []*Problem
-
-	var helpable_docked_ships []*hal.Ship
-
-	for _, ship := range self.Game.MyShips() {
-		if ship.DockedStatus != hal.UNDOCKED && ship.Doomed == false {
-			helpable_docked_ships = append(helpable_docked_ships, ship)
+		if reg.fallback_for != "" && produced[reg.fallback_for] {
+			continue
 		}
-	}
-
-	if len(helpable_docked_ships) > 0 {
-
-		for _, ship := range helpable_docked_ships {
 
-			problem := &Problem{
-				Entity: ship,
-				Value: 1.0,
-				Need: 1,
-				Message: ship.Id,
-			}
-			problems = append(problems, problem)
+		weight, ok := self.ProblemWeights[source.Name()]
+		if ok == false {
+			weight = ProblemWeight{Value: 1}
 		}
 
-	} else {
-
-		relevant_enemies := self.Game.ShipsOwnedBy(self.RushEnemyID)
-
-		some_are_docked := false
-
-		for _, ship := range relevant_enemies {
-			if ship.DockedStatus != hal.UNDOCKED {
-				some_are_docked = true
-				break
-			}
+		if weight.Off {
+			continue
 		}
 
-		for _, ship := range relevant_enemies {
-
-			if ship.DockedStatus != hal.UNDOCKED || some_are_docked == false {
-
-				if ship.Doomed == false {
-
-					problem := &Problem{
-						Entity: ship,
-						Value: 1.0,
-						Need: 1,
-						Message: ship.Id,
-					}
-
-					problems = append(problems, problem)
-				}
+		for _, problem := range source.Problems(self, planet) {
+			problem.Value *= weight.Value
+			problem.Need += weight.Need
+			if problem.Value > 0 && problem.Need > 0 {
+				ret = append(ret, problem)
+				produced[source.Name()] = true
 			}
 		}
 	}
 
-	return problems
+	return ret
 }
 
 // -------------------------------------------------------------------------------
@@ -206,6 +138,11 @@ func (self *Overmind) OptimisePilots() {
 
 		for i := 0; i < len(self.Pilots); i++ {
 
+			if self.Game.Budget().Used(OPTIMISE_BUDGET) {		// Whatever swaps we've made so far are improvements, so just stop.
+				self.Game.Log("OptimisePilots(): out of time")
+				return
+			}
+
 			pilot_a := self.Pilots[i]
 
 			if pilot_a.DockedStatus != hal.UNDOCKED || pilot_a.Locked {
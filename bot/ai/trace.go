@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"sort"
+
+	hal "../core"
+)
+
+// One TurnTrace per turn is written to the game's trace (MyBot's -trace flag), as a line of
+// JSON. It holds what the Debug*() functions can only log for a single DEBUG_SHIP_ID, but for
+// every pilot on every turn. The same structs are used to read it back, e.g. in cmd/trace.
+
+type TurnTrace struct {
+	Turn				int					`json:"turn"`
+	Pid					int					`json:"pid"`
+	Strategy			string				`json:"strategy"`
+	RushChoice			string				`json:"rush_choice"`
+	CowardFlag			bool				`json:"coward"`
+	AvoidingBad2v1		bool				`json:"avoiding_bad_2v1"`
+	Pilots				[]*PilotTrace		`json:"pilots"`
+}
+
+type PilotTrace struct {
+	Sid					int					`json:"sid"`
+	X					float64				`json:"x"`
+	Y					float64				`json:"y"`
+	Docked				bool				`json:"docked"`					// Any state but UNDOCKED
+	Target				string				`json:"target"`					// "" for no target
+	Locked				bool				`json:"locked"`
+	Plan				string				`json:"plan"`
+	Order				string				`json:"order"`					// As Step() left it; Send() may yet repair it (see core/validate.go)
+	Message				int					`json:"message"`
+	NavStack			[]string			`json:"nav_stack"`
+	Inhibition			float64				`json:"inhibition"`
+	DangerShips			[]int				`json:"danger_ships"`
+	Fleeing				bool				`json:"fleeing"`
+}
+
+var rush_choice_names = map[int]string{
+	NOT_RUSHING: "not rushing",
+	UNDECIDED: "undecided",
+	RUSHING: "rushing",
+}
+
+func (self *Overmind) MakeTurnTrace() *TurnTrace {
+
+	ret := &TurnTrace{
+		Turn: self.Game.Turn(),
+		Pid: self.Game.Pid(),
+		RushChoice: rush_choice_names[self.RushChoice],
+		CowardFlag: self.CowardFlag,
+		AvoidingBad2v1: self.AvoidingBad2v1,
+	}
+
+	if self.Strategy != nil {
+		ret.Strategy = self.Strategy.Name()
+	}
+
+	for _, pilot := range self.Pilots {
+
+		pt := &PilotTrace{
+			Sid: pilot.Id,
+			X: pilot.X,
+			Y: pilot.Y,
+			Docked: pilot.DockedStatus != hal.UNDOCKED,
+			Locked: pilot.Locked,
+			Plan: pilot.Plan.String(),
+			Order: self.Game.CurrentOrder(pilot.Ship).Wire(false),
+			Message: pilot.Message,
+			NavStack: pilot.NavStack,
+			Inhibition: pilot.Inhibition,
+			Fleeing: pilot.Fleeing,
+		}
+
+		if pilot.HasTarget() {
+			pt.Target = pilot.Target.String()
+		}
+
+		for _, ship := range pilot.DangerShips {
+			pt.DangerShips = append(pt.DangerShips, ship.Id)
+		}
+
+		ret.Pilots = append(ret.Pilots, pt)
+	}
+
+	sort.Slice(ret.Pilots, func(a, b int) bool {
+		return ret.Pilots[a].Sid < ret.Pilots[b].Sid
+	})
+
+	return ret
+}
+
+// Trace writes this turn's TurnTrace, if the game is tracing. Called at the end of Step().
+
+func (self *Overmind) Trace() {
+	if self.Game.Tracing() {
+		self.Game.Trace(self.MakeTurnTrace())
+	}
+}
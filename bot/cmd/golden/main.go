@@ -0,0 +1,256 @@
+package main
+
+// Regression check: replays every reference game through Overmind.Step() (as our own
+// player) and compares the orders with those saved in the golden directory. Run from
+// the bot directory:
+//
+//     golden                       check all replays
+//     golden -update               accept current behaviour as the new golden
+//     golden "v64 - you hold em off 1"   check just the named games
+//     golden -strict               also fail any turn whose orders break the game's rules
+//
+// Note that Step() sees the historical game, not the consequences of its own orders,
+// so a change on one turn doesn't cascade into every later turn. There's no time limit
+// (the GA always runs all its iterations), so results don't depend on the machine's speed.
+//
+// "go test" checks a few quick games the same way (see main_test.go).
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	ai "../../ai"
+	nav "../../navigation"
+	rep "../../replay"
+)
+
+func main() {
+
+	var replay_dir, golden_dir string
+	var update, verbose, strict bool
+
+	flag.StringVar(&replay_dir, "replays", "../reference replays", "directory of .hlt files")
+	flag.StringVar(&golden_dir, "golden", "golden", "directory of golden files")
+	flag.BoolVar(&update, "update", false, "write golden files instead of checking them")
+	flag.BoolVar(&verbose, "v", false, "show the old and new orders for changed turns")
+	flag.BoolVar(&strict, "strict", false, "validate every turn's orders (see core/validate.go)")
+	flag.Parse()
+
+	nav.Ignore_Collision_Dist = 100				// As per MyBot's default flags
+
+	files, err := filepath.Glob(filepath.Join(replay_dir, "*.hlt"))
+	if err != nil || len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "No replays found in \"%s\"\n", replay_dir)
+		os.Exit(1)
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range flag.Args() {
+		wanted[strings.TrimSuffix(name, ".hlt")] = true
+	}
+
+	changed_games := 0
+
+	for _, filename := range files {
+
+		name := strings.TrimSuffix(filepath.Base(filename), ".hlt")
+
+		if len(wanted) > 0 && wanted[name] == false {
+			continue
+		}
+
+		orders, bad_orders, err := run_replay(filename, strict)
+		if err != nil {
+			fmt.Printf("%s: %v\n", name, err)
+			changed_games++
+			continue
+		}
+
+		for _, bad := range bad_orders {
+			fmt.Printf("%s: %v\n", name, bad)
+		}
+
+		golden_file := filepath.Join(golden_dir, name + ".txt")
+
+		if update {
+			err = save_golden(golden_file, orders)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s: wrote %d turns\n", name, len(orders))
+			continue
+		}
+
+		golden, err := load_golden(golden_file)
+		if err != nil {
+			fmt.Printf("%s: %v\n", name, err)
+			changed_games++
+			continue
+		}
+
+		changed_turns := compare_orders(golden, orders)
+
+		if verbose {
+			for _, turn := range changed_turns {
+				var old, now string
+				if turn < len(golden) { old = golden[turn] }
+				if turn < len(orders) { now = orders[turn] }
+				fmt.Printf("%s: turn %d\n    was: %s\n    now: %s\n", name, turn, old, now)
+			}
+		}
+
+		if len(changed_turns) > 0 {
+			fmt.Printf("%s: %d turns changed: %v\n", name, len(changed_turns), changed_turns)
+		}
+
+		if len(changed_turns) > 0 || len(bad_orders) > 0 {
+			changed_games++
+		}
+	}
+
+	if update == false {
+		if changed_games > 0 {
+			fmt.Printf("%d games changed\n", changed_games)
+			os.Exit(1)
+		}
+		fmt.Printf("OK\n")
+	}
+}
+
+func run_replay(filename string, strict bool) (orders []string, bad_orders []error, err error) {
+
+	replay, err := rep.Load(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pid := -1
+	for i, name := range replay.PlayerNames {
+		if strings.HasPrefix(strings.ToLower(name), "fohristiwhirl") {
+			pid = i
+		}
+	}
+	if pid == -1 {
+		return nil, nil, fmt.Errorf("couldn't find our own player in %v", replay.PlayerNames)
+	}
+
+	playback, err := rep.NewPlayback(replay, pid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	game := playback.Game()
+	game.Budget().SetLimit(0)
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic on turn %d: %v", game.Turn(), p)
+		}
+	}()
+
+	overmind := ai.NewOvermind(game, new(ai.Config))
+
+	for turn := 0; turn < len(replay.Frames); turn++ {
+
+		err = playback.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rand.Seed(int64(game.Turn() + game.Width() + game.Pid()))		// As MyBot does
+
+		overmind.Step()
+
+		if strict {
+			if bad := game.ValidateOrders(true); bad != nil {
+				bad_orders = append(bad_orders, bad)
+			}
+		}
+
+		orders = append(orders, game.RawOutput(true, false))
+	}
+
+	return orders, bad_orders, nil
+}
+
+// compare_orders returns the turns whose orders differ between the golden and new lists.
+
+func compare_orders(golden, orders []string) []int {
+
+	var changed_turns []int
+
+	for turn := 0; turn < len(orders) || turn < len(golden); turn++ {
+
+		var old, now string
+		if turn < len(golden) { old = golden[turn] }
+		if turn < len(orders) { now = orders[turn] }
+
+		if old != now {
+			changed_turns = append(changed_turns, turn)
+		}
+	}
+
+	return changed_turns
+}
+
+// Golden files have one line per turn: the turn number, a colon, then the orders.
+
+func save_golden(filename string, orders []string) error {
+
+	err := os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return err
+	}
+
+	outfile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(outfile)
+	for turn, s := range orders {
+		fmt.Fprintf(w, "%d: %s\n", turn, s)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		outfile.Close()
+		return err
+	}
+	return outfile.Close()
+}
+
+func load_golden(filename string) ([]string, error) {
+
+	infile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+
+	var ret []string
+
+	scanner := bufio.NewScanner(infile)
+	scanner.Buffer(make([]byte, 64 * 1024), 16 * 1024 * 1024)
+
+	for scanner.Scan() {
+
+		fields := strings.SplitN(scanner.Text(), ":", 2)
+		turn, err := strconv.Atoi(fields[0])
+
+		if len(fields) != 2 || err != nil || turn != len(ret) {
+			return nil, fmt.Errorf("%s: bad line %d", filename, len(ret) + 1)
+		}
+
+		ret = append(ret, strings.TrimPrefix(fields[1], " "))
+	}
+
+	return ret, scanner.Err()
+}
@@ -0,0 +1,77 @@
+package main
+
+// The golden check as a test. By default only a couple of quick games are checked, since the
+// full set takes about a quarter of an hour:
+//
+//     go test ./cmd/golden                                   the quick games
+//     go test ./cmd/golden -timeout 1h -args -golden.all     every game
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	nav "../../navigation"
+)
+
+const (
+	TEST_REPLAY_DIR = "../../../reference replays"
+	TEST_GOLDEN_DIR = "../../golden"
+)
+
+var TEST_QUICK_GAMES = []string{
+	"v64 - you hold em off 2",
+	"v74 - rush defense",
+}
+
+var golden_all = flag.Bool("golden.all", false, "check every reference replay, not just the quick ones")
+
+func TestGolden(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipped in -short mode")
+	}
+
+	if _, err := os.Stat(TEST_REPLAY_DIR); err != nil {
+		t.Skipf("no reference replays: %v", err)
+	}
+
+	nav.Ignore_Collision_Dist = 100				// As main() does
+
+	names := TEST_QUICK_GAMES
+
+	if *golden_all {
+		files, err := filepath.Glob(filepath.Join(TEST_REPLAY_DIR, "*.hlt"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("no replays found in \"%s\"", TEST_REPLAY_DIR)
+		}
+		names = nil
+		for _, filename := range files {
+			names = append(names, strings.TrimSuffix(filepath.Base(filename), ".hlt"))
+		}
+	}
+
+	for _, name := range names {
+
+		name := name
+
+		t.Run(name, func(t *testing.T) {
+
+			orders, _, err := run_replay(filepath.Join(TEST_REPLAY_DIR, name + ".hlt"), false)	// Not strict: some old games have bad orders.
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			golden, err := load_golden(filepath.Join(TEST_GOLDEN_DIR, name + ".txt"))
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if changed_turns := compare_orders(golden, orders); len(changed_turns) > 0 {
+				t.Errorf("%d turns changed: %v", len(changed_turns), changed_turns)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+// Learns a policy for the "policy" enemy model of the rush GA from a directory of replays.
+// By default only opponents' ships are learnt from, not our own. Run from the bot directory:
+//
+//     learnpolicy -out policy.json
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gen "../../genetic"
+	rep "../../replay"
+)
+
+func main() {
+
+	var replay_dir, outfilename string
+	var all bool
+
+	flag.StringVar(&replay_dir, "replays", "../reference replays", "directory of .hlt files")
+	flag.StringVar(&outfilename, "out", "policy.json", "output file")
+	flag.BoolVar(&all, "all", false, "learn from our own ships too")
+	flag.Parse()
+
+	files, err := filepath.Glob(filepath.Join(replay_dir, "*.hlt"))
+	if err != nil || len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "No replays found in \"%s\"\n", replay_dir)
+		os.Exit(1)
+	}
+
+	policy := gen.NewPolicyModel()
+
+	for _, filename := range files {
+
+		replay, err := rep.Load(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+			continue
+		}
+
+		for pid, name := range replay.PlayerNames {
+			if all || strings.HasPrefix(strings.ToLower(name), "fohristiwhirl") == false {
+				policy.Learn(replay, pid)
+			}
+		}
+	}
+
+	err = policy.Save(outfilename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Learnt %d situations from %d replays\n", len(policy.Counts), len(files))
+}
@@ -0,0 +1,84 @@
+package main
+
+// Plays a local game between 2 or 4 bot executables, e.g.
+//
+//     match -seed 42 -replay game.hlt "./MyBot" "../basic/MyBot"
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	eng "../../engine"
+)
+
+func main() {
+
+	var seed int64
+	var width, height, turn_ms int
+	var no_timeout, verbose bool
+	var replay_file string
+
+	flag.Int64Var(&seed, "seed", 0, "map seed (0 means use the time)")
+	flag.IntVar(&width, "width", 0, "map width (0 means choose from seed)")
+	flag.IntVar(&height, "height", 0, "map height (0 means choose from seed)")
+	flag.IntVar(&turn_ms, "turntime", 2000, "time allowed per turn, in milliseconds")
+	flag.BoolVar(&no_timeout, "notimeout", false, "no time limits at all (for debugging)")
+	flag.BoolVar(&verbose, "v", false, "print ignored orders")
+	flag.StringVar(&replay_file, "replay", "", "save a replay (.hlt) of the game here")
+
+	flag.Parse()
+
+	if flag.NArg() != 2 && flag.NArg() != 4 {
+		fmt.Fprintf(os.Stderr, "Usage: match [flags] bot1 bot2 [bot3 bot4]\n")
+		os.Exit(1)
+	}
+
+	if seed == 0 {
+		seed = time.Now().UTC().UnixNano() % 4294967296
+	}
+
+	config := eng.DefaultMatchConfig(seed)
+	config.Width = width
+	config.Height = height
+	config.TurnTimeout = time.Duration(turn_ms) * time.Millisecond
+	config.ReplayFile = replay_file
+
+	if no_timeout {
+		config.InitTimeout = 0
+		config.TurnTimeout = 0
+	}
+
+	config.Log = func(format_string string, args ...interface{}) {
+		if verbose {
+			fmt.Fprintf(os.Stderr, format_string + "\n", args...)
+		}
+	}
+
+	var bots []eng.Bot
+
+	for _, command := range flag.Args() {
+		bot, err := eng.NewProcessBot(command)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't start \"%s\": %v\n", command, err)
+			os.Exit(1)
+		}
+		bots = append(bots, bot)
+	}
+
+	result, err := eng.RunMatch(bots, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		if result == nil {
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("Seed %d, map %dx%d, %d turns\n", result.Seed, result.Width, result.Height, result.Turns)
+
+	for pid, stats := range result.Stats {
+		fmt.Printf("  #%d  player %d  %-30s  alive until %3d, made %3d ships, dealt %6d damage  %s\n",
+			result.Ranks[pid], pid, stats.Name, stats.LastFrameAlive, stats.ShipsProduced, stats.DamageDealt, stats.Error)
+	}
+}
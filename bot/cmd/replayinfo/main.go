@@ -0,0 +1,79 @@
+package main
+
+// Summarises .hlt replays, e.g.
+//
+//     replayinfo "../reference replays/"*.hlt
+//     replayinfo -frame 40 "../reference replays/v99 - chase chase.hlt"
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	rep "../../replay"
+)
+
+func main() {
+
+	var frame int
+
+	flag.IntVar(&frame, "frame", -1, "also dump this frame's ships, planets, events and moves")
+	flag.Parse()
+
+	failed := false
+
+	for _, filename := range flag.Args() {
+
+		r, err := rep.Load(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			failed = true
+			continue
+		}
+
+		fmt.Printf("%s\n", filename)
+		fmt.Printf("  %dx%d, seed %d, %d frames\n", r.Width, r.Height, r.Seed, len(r.Frames))
+
+		for pid, name := range r.PlayerNames {
+			stats := r.Stats[pid]
+			fmt.Printf("  #%d  player %d  %-30s  alive until %3d, dealt %6d damage\n",
+				stats.Rank, pid, name, stats.LastFrameAlive, stats.DamageDealt)
+		}
+
+		if frame >= 0 && frame < len(r.Frames) {
+			dump_frame(r, frame)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func dump_frame(r *rep.Replay, n int) {
+
+	f := r.Frames[n]
+
+	fmt.Printf("  Frame %d:\n", n)
+
+	for _, ship := range f.Ships {
+		fmt.Printf("    ship %3d  owner %d  [%7.2f, %7.2f]  hp %3d  docked %d (planet %d, %d)\n",
+			ship.Id, ship.Owner, ship.X, ship.Y, ship.HP, ship.DockedStatus, ship.DockedPlanet, ship.TurnsLeft)
+	}
+
+	for _, planet := range f.Planets {
+		info := r.Planets[planet.Id]
+		fmt.Printf("    planet %2d  owner %2d  [%7.2f, %7.2f]  r %5.2f  hp %4d  docked %v\n",
+			planet.Id, planet.Owner, info.X, info.Y, info.Radius, planet.HP, planet.DockedShips)
+	}
+
+	for _, event := range f.Events {
+		fmt.Printf("    event %-9s  %s %d  t %.4f\n", event.Type, event.Entity.Type, event.Entity.Id, event.Time)
+	}
+
+	if n < len(r.Moves) {
+		for _, move := range r.Moves[n] {
+			fmt.Printf("    move by %d: %v\n", move.Owner, move)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+// Checks genetic.FullSim against replays: each turn, a FullSim is seeded from the parsed frame,
+// given every player's actual moves, and stepped; the result is compared with the next frame.
+// Run from the bot directory:
+//
+//     simcheck                                     check all the reference replays
+//     simcheck -v "v99 - chase chase"              check one, showing every mismatch
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	hal "../../core"
+	gen "../../genetic"
+	rep "../../replay"
+)
+
+type tally struct {
+	ships				int					// Ships in the real next frames
+	wrong				int					// ...which the sim got wrong (moved, hurt or docked differently, or lost)
+	extra				int					// Ships the sim has which the real game doesn't
+}
+
+func main() {
+
+	var replay_dir string
+	var verbose bool
+
+	flag.StringVar(&replay_dir, "replays", "../reference replays", "directory of .hlt files")
+	flag.BoolVar(&verbose, "v", false, "show every mismatch")
+	flag.Parse()
+
+	files, err := filepath.Glob(filepath.Join(replay_dir, "*.hlt"))
+	if err != nil || len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "No replays found in \"%s\"\n", replay_dir)
+		os.Exit(1)
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range flag.Args() {
+		wanted[strings.TrimSuffix(name, ".hlt")] = true
+	}
+
+	var total tally
+
+	for _, filename := range files {
+
+		name := strings.TrimSuffix(filepath.Base(filename), ".hlt")
+
+		if len(wanted) > 0 && wanted[name] == false {
+			continue
+		}
+
+		result, err := check_replay(filename, name, verbose)
+		if err != nil {
+			fmt.Printf("%s: %v\n", name, err)
+			continue
+		}
+
+		fmt.Printf("%s: %d / %d ships wrong, %d extra\n", name, result.wrong, result.ships, result.extra)
+
+		total.ships += result.ships
+		total.wrong += result.wrong
+		total.extra += result.extra
+	}
+
+	if total.ships > 0 {
+		fmt.Printf("Total: %d / %d ships wrong (%.2f%%), %d extra\n",
+			total.wrong, total.ships, float64(total.wrong) * 100 / float64(total.ships), total.extra)
+	}
+}
+
+func check_replay(filename, name string, verbose bool) (tally, error) {
+
+	var ret tally
+
+	replay, err := rep.Load(filename)
+	if err != nil {
+		return ret, err
+	}
+
+	playback, err := rep.NewPlayback(replay, 0)			// Any player will do, the sim sees everything.
+	if err != nil {
+		return ret, err
+	}
+
+	game := playback.Game()
+
+	err = playback.Next()
+	if err != nil {
+		return ret, err
+	}
+
+	for turn := 0; turn < len(replay.Frames) - 1; turn++ {
+
+		sim := gen.NewFullSim(game)
+
+		for pid := 0; pid < replay.NumPlayers; pid++ {
+			for _, move := range replay.MovesBy(turn, pid) {
+				switch move.Type {
+				case rep.THRUST:
+					sim.Thrust(move.ShipId, move.Magnitude, move.Angle)
+				case rep.DOCK:
+					sim.Dock(move.ShipId, move.PlanetId)
+				case rep.UNDOCK:
+					sim.Undock(move.ShipId)
+				}
+			}
+		}
+
+		sim.Step()
+
+		err = playback.Next()
+		if err != nil {
+			return ret, err
+		}
+
+		// The Game parses the next frame the same way NewFullSim() was seeded, docking status and all.
+
+		for _, ship := range game.AllShips() {
+
+			ret.ships++
+
+			sim_ship, ok := sim.GetShip(ship.Id)
+
+			if ok == false {
+				ret.wrong++
+				if verbose {
+					fmt.Printf("%s: turn %d: ship %d missing from sim\n", name, turn + 1, ship.Id)
+				}
+				continue
+			}
+
+			if same_ship(ship, sim_ship) == false {
+				ret.wrong++
+				if verbose {
+					fmt.Printf("%s: turn %d: ship %d was [%.2f, %.2f] hp %d %v, sim [%.2f, %.2f] hp %d %v\n",
+						name, turn + 1, ship.Id,
+						ship.X, ship.Y, ship.HP, ship.DockedStatus,
+						sim_ship.X(), sim_ship.Y(), sim_ship.HP(), sim_ship.DockedStatus())
+				}
+			}
+		}
+
+		for _, sim_ship := range sim.Ships() {
+			if _, ok := game.GetShip(sim_ship.Id()); ok == false {
+				ret.extra++
+				if verbose {
+					fmt.Printf("%s: turn %d: sim has ship %d, which the game doesn't\n", name, turn + 1, sim_ship.Id())
+				}
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+func same_ship(ship *hal.Ship, sim_ship *gen.SimShip) bool {
+
+	if math.Abs(ship.X - sim_ship.X()) > 0.01 || math.Abs(ship.Y - sim_ship.Y()) > 0.01 {
+		return false
+	}
+
+	return ship.HP == sim_ship.HP() && ship.DockedStatus == sim_ship.DockedStatus()
+}
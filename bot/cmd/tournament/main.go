@@ -0,0 +1,311 @@
+package main
+
+// Plays round-robin 2 and/or 4 player games between bot variants on generated maps, rating
+// them with TrueSkill as the real ladder did. Build MyBot first; then, from the bot directory:
+//
+//     tournament -rounds 5 -- -conservative -forcerush -centre "../basic/MyBot"
+//
+// An entrant starting with "-" is MyBot (see -bot) with those flags; anything else is a command.
+// After each round the leaderboard and per-matchup statistics are printed and written to -out.
+//
+// Each round, in 2 player mode, every pair plays twice (once from each seat); in 4 player mode,
+// every set of 4 entrants plays once, with seats rotating from round to round. Games within a
+// round run in parallel but are rated in a fixed order, so results depend only on the seed.
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	hal "../../core"
+	eng "../../engine"
+)
+
+type entrant struct {
+	name			string
+	command			string
+	rating			Rating
+	games			int
+	firsts			int
+}
+
+type fixture struct {
+	seats			[]int			// Entrant index in each seat
+	seed			int64
+}
+
+type outcome struct {
+	order			[]int			// Entrant indices, winner first
+	err				error
+}
+
+type tournament struct {
+	entrants		[]*entrant
+	turn_timeout	time.Duration
+	parallel		int
+	beat			[][]int			// [a][b] --> games where a finished above b
+	met				[][]int
+}
+
+// play runs one game. Games run in parallel, so each gets its own working directory for the
+// bots' log files.
+
+func (self *tournament) play(f fixture) outcome {
+
+	work_dir, err := ioutil.TempDir("", "tournament_game")
+	if err != nil {
+		return outcome{err: err}
+	}
+	defer os.RemoveAll(work_dir)
+
+	var bots []eng.Bot
+
+	for _, e := range f.seats {
+		bot, err := eng.NewProcessBotIn(self.entrants[e].command, work_dir)
+		if err != nil {
+			for _, b := range bots {
+				b.Close()
+			}
+			return outcome{err: fmt.Errorf("couldn't start \"%s\": %v", self.entrants[e].command, err)}
+		}
+		bots = append(bots, bot)
+	}
+
+	config := eng.DefaultMatchConfig(f.seed)
+	config.TurnTimeout = self.turn_timeout
+
+	result, err := eng.RunMatch(bots, config)
+	if err != nil {
+		return outcome{err: err}
+	}
+
+	pids := make([]int, len(f.seats))
+	for pid := range pids {
+		pids[pid] = pid
+	}
+
+	sort.SliceStable(pids, func(a, b int) bool {
+		return result.Ranks[pids[a]] < result.Ranks[pids[b]]
+	})
+
+	var order []int
+	for _, pid := range pids {
+		order = append(order, f.seats[pid])
+	}
+
+	return outcome{order: order}
+}
+
+func (self *tournament) play_all(fixtures []fixture) []outcome {
+
+	ret := make([]outcome, len(fixtures))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < self.parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ret[i] = self.play(fixtures[i])
+			}
+		}()
+	}
+
+	for i := range fixtures {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return ret
+}
+
+func (self *tournament) rate(o outcome) {
+
+	var ratings []Rating
+	for _, e := range o.order {
+		ratings = append(ratings, self.entrants[e].rating)
+	}
+
+	for i, r := range UpdateRatings(ratings) {
+		self.entrants[o.order[i]].rating = r
+	}
+
+	for i, a := range o.order {
+		self.entrants[a].games++
+		if i == 0 {
+			self.entrants[a].firsts++
+		}
+		for _, b := range o.order[i + 1:] {
+			self.beat[a][b]++
+			self.met[a][b]++
+			self.met[b][a]++
+		}
+	}
+}
+
+func (self *tournament) report(w io.Writer, round int) {
+
+	sorted := append([]*entrant(nil), self.entrants...)
+
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return sorted[a].rating.Conservative() > sorted[b].rating.Conservative()
+	})
+
+	fmt.Fprintf(w, "After round %d\n\n", round)
+	fmt.Fprintf(w, "       score      mu   sigma  games  firsts  name\n")
+
+	for i, e := range sorted {
+		fmt.Fprintf(w, "  %2d  %6.2f  %6.2f  %6.2f  %5d  %6d  %s\n",
+			i + 1, e.rating.Conservative(), e.rating.Mu, e.rating.Sigma, e.games, e.firsts, e.name)
+	}
+
+	fmt.Fprintf(w, "\nMatchups (row finished above column / games together):\n\n")
+
+	for a := range self.entrants {
+		fmt.Fprintf(w, "  %2d", a)
+		for b := range self.entrants {
+			if a == b {
+				fmt.Fprintf(w, "  %9s", "-")
+			} else {
+				fmt.Fprintf(w, "  %4d/%-4d", self.beat[a][b], self.met[a][b])
+			}
+		}
+		fmt.Fprintf(w, "  %s\n", self.entrants[a].name)
+	}
+
+	fmt.Fprintf(w, "\n")
+}
+
+// --------------------------------------------
+
+// combinations returns every k-subset of 0..n-1, in lexicographic order.
+
+func combinations(n, k int) [][]int {
+
+	var ret [][]int
+	var rec func(start int, current []int)
+
+	rec = func(start int, current []int) {
+		if len(current) == k {
+			ret = append(ret, append([]int(nil), current...))
+			return
+		}
+		for i := start; i < n; i++ {
+			rec(i + 1, append(current, i))
+		}
+	}
+
+	rec(0, nil)
+	return ret
+}
+
+func round_fixtures(n, round int, two, four bool, next_seed func() int64) []fixture {
+
+	var ret []fixture
+
+	if two {
+		for _, pair := range combinations(n, 2) {
+			ret = append(ret, fixture{[]int{pair[0], pair[1]}, next_seed()})
+			ret = append(ret, fixture{[]int{pair[1], pair[0]}, next_seed()})
+		}
+	}
+
+	if four && n >= 4 {
+		for _, set := range combinations(n, 4) {
+			seats := make([]int, 4)
+			for i := range seats {
+				seats[i] = set[(i + round) % 4]
+			}
+			ret = append(ret, fixture{seats, next_seed()})
+		}
+	}
+
+	return ret
+}
+
+func main() {
+
+	var bot, mode, out_file string
+	var rounds, parallel, turn_ms int
+	var seed int64
+
+	flag.StringVar(&bot, "bot", "./MyBot", "executable for entrants given as flags")
+	flag.StringVar(&mode, "mode", "both", "2, 4 or both")
+	flag.StringVar(&out_file, "out", "leaderboard.txt", "leaderboard file, rewritten after each round")
+	flag.IntVar(&rounds, "rounds", 5, "rounds of the round robin")
+	flag.IntVar(&parallel, "parallel", 2, "games played at once")
+	flag.IntVar(&turn_ms, "turntime", 2000, "time allowed per turn, in milliseconds")
+	flag.Int64Var(&seed, "seed", 1, "first map seed")
+	flag.Parse()
+
+	two := mode == "2" || mode == "both"
+	four := mode == "4" || mode == "both"
+
+	if two == false && four == false {
+		fmt.Fprintf(os.Stderr, "-mode must be 2, 4 or both\n")
+		os.Exit(1)
+	}
+
+	if flag.NArg() < 2 || (two == false && flag.NArg() < 4) {
+		fmt.Fprintf(os.Stderr, "Usage: tournament [flags] -- entrant entrant [entrant...]\n")
+		os.Exit(1)
+	}
+
+	t := &tournament{
+		turn_timeout: time.Duration(turn_ms) * time.Millisecond,
+		parallel: hal.Max(1, parallel),
+	}
+
+	for _, arg := range flag.Args() {
+		e := &entrant{name: arg, command: arg, rating: NewRating()}
+		if strings.HasPrefix(arg, "-") {
+			e.command = bot + " " + arg
+		}
+		t.entrants = append(t.entrants, e)
+	}
+
+	n := len(t.entrants)
+
+	for i := 0; i < n; i++ {
+		t.beat = append(t.beat, make([]int, n))
+		t.met = append(t.met, make([]int, n))
+	}
+
+	next_seed := func() int64 {
+		seed++
+		return seed - 1
+	}
+
+	for round := 0; round < rounds; round++ {
+
+		fixtures := round_fixtures(n, round, two, four, next_seed)
+
+		for i, o := range t.play_all(fixtures) {
+			if o.err != nil {
+				fmt.Fprintf(os.Stderr, "Seed %d: %v\n", fixtures[i].seed, o.err)
+				continue
+			}
+			t.rate(o)
+		}
+
+		t.report(os.Stdout, round)
+
+		outfile, err := os.Create(out_file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			continue
+		}
+		t.report(outfile, round)
+		outfile.Close()
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+// TrueSkill for free-for-all games of single players, no draws, with the usual defaults (as on
+// the Halite ladder). The ranking is a chain of "p[k] beats p[k+1]" factors, and messages are
+// passed up and down the chain until they settle, as in Herbrich, Minka & Graepel (2007).
+
+import (
+	"math"
+)
+
+const (
+	TS_MU = 25.0
+	TS_SIGMA = TS_MU / 3
+	TS_BETA = TS_SIGMA / 2
+	TS_TAU = TS_SIGMA / 100
+)
+
+type Rating struct {
+	Mu					float64
+	Sigma				float64
+}
+
+func NewRating() Rating {
+	return Rating{TS_MU, TS_SIGMA}
+}
+
+// Conservative is what the leaderboard is sorted by: we're fairly sure the skill is above it.
+
+func (self Rating) Conservative() float64 {
+	return self.Mu - 3 * self.Sigma
+}
+
+// gaussian is in natural parameters (precision and precision-adjusted mean), since messages
+// are multiplied and divided far more often than they're read. The zero value is uniform.
+
+type gaussian struct {
+	pi					float64
+	tau					float64
+}
+
+func from_mean_var(mean, variance float64) gaussian {
+	return gaussian{1 / variance, mean / variance}
+}
+
+func (self gaussian) mean() float64 { return self.tau / self.pi }
+func (self gaussian) variance() float64 { return 1 / self.pi }
+func (self gaussian) mul(other gaussian) gaussian { return gaussian{self.pi + other.pi, self.tau + other.tau} }
+func (self gaussian) div(other gaussian) gaussian { return gaussian{self.pi - other.pi, self.tau - other.tau} }
+
+func pdf(x float64) float64 { return math.Exp(-x * x / 2) / math.Sqrt(2 * math.Pi) }
+func cdf(x float64) float64 { return (1 + math.Erf(x / math.Sqrt2)) / 2 }
+
+// v and w are the mean and variance corrections for a Gaussian truncated to x > 0.
+
+func v_win(t float64) float64 {
+	denom := cdf(t)
+	if denom < 1e-160 {
+		return -t
+	}
+	return pdf(t) / denom
+}
+
+func w_win(t float64) float64 {
+	v := v_win(t)
+	return v * (v + t)
+}
+
+// UpdateRatings takes ratings in finishing order (winner first) and returns the new ones.
+
+func UpdateRatings(ratings []Rating) []Rating {
+
+	n := len(ratings)
+
+	if n < 2 {
+		return append([]Rating(nil), ratings...)
+	}
+
+	skill := make([]gaussian, n)
+	perf := make([]gaussian, n)						// Message from each skill to its performance
+
+	for i, r := range ratings {
+		variance := r.Sigma * r.Sigma + TS_TAU * TS_TAU
+		skill[i] = from_mean_var(r.Mu, variance)
+		perf[i] = from_mean_var(r.Mu, variance + TS_BETA * TS_BETA)
+	}
+
+	// Factor k says perf[k] - perf[k + 1] > 0. Its messages to either side, and the message
+	// from the truncation to the difference, start uniform.
+
+	to_a := make([]gaussian, n - 1)
+	to_b := make([]gaussian, n - 1)
+	trunc := make([]gaussian, n - 1)
+
+	update := func(k int) float64 {
+
+		a_in := perf[k]
+		if k > 0 {
+			a_in = a_in.mul(to_b[k - 1])
+		}
+
+		b_in := perf[k + 1]
+		if k + 1 < n - 1 {
+			b_in = b_in.mul(to_a[k + 1])
+		}
+
+		d_in := from_mean_var(a_in.mean() - b_in.mean(), a_in.variance() + b_in.variance())
+
+		sqrt_pi := math.Sqrt(d_in.pi)
+		t := d_in.tau / sqrt_pi
+		w := w_win(t)
+		marginal := gaussian{d_in.pi / (1 - w), (d_in.tau + sqrt_pi * v_win(t)) / (1 - w)}
+
+		new_trunc := marginal.div(d_in)
+		delta := math.Abs(new_trunc.tau - trunc[k].tau) + math.Abs(new_trunc.pi - trunc[k].pi)
+		trunc[k] = new_trunc
+
+		to_a[k] = from_mean_var(trunc[k].mean() + b_in.mean(), trunc[k].variance() + b_in.variance())
+		to_b[k] = from_mean_var(a_in.mean() - trunc[k].mean(), a_in.variance() + trunc[k].variance())
+
+		return delta
+	}
+
+	for iteration := 0; iteration < 50; iteration++ {
+
+		delta := 0.0
+
+		for k := 0; k < n - 1; k++ {
+			delta = math.Max(delta, update(k))
+		}
+		for k := n - 2; k >= 0; k-- {
+			delta = math.Max(delta, update(k))
+		}
+
+		if delta < 0.0001 {
+			break
+		}
+	}
+
+	ret := make([]Rating, n)
+
+	for i := range ratings {
+
+		var from_games gaussian							// Everything the ranking says about this performance
+		if i > 0 {
+			from_games = from_games.mul(to_b[i - 1])
+		}
+		if i < n - 1 {
+			from_games = from_games.mul(to_a[i])
+		}
+
+		msg := from_mean_var(from_games.mean(), from_games.variance() + TS_BETA * TS_BETA)
+		posterior := skill[i].mul(msg)
+
+		ret[i] = Rating{posterior.mean(), math.Sqrt(posterior.variance())}
+	}
+
+	return ret
+}
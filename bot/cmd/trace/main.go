@@ -0,0 +1,187 @@
+package main
+
+// Queries the JSON-lines traces written by MyBot -trace, e.g.
+//
+//     trace -turn 40 trace0.jsonl                       everything on turn 40
+//     trace -from 30 -to 50 -ship 7 trace0.jsonl        ship 7's pilot over a range of turns
+//     trace -ship 7 -fields target,order,nav_stack trace0.jsonl
+//     trace -fleeing trace0.jsonl                       only pilots that were fleeing
+//     trace -json -turn 40 trace0.jsonl                 matching records, as JSON lines again
+//
+// Fields are named as in the JSON (see ai/trace.go).
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	ai "../../ai"
+)
+
+type query struct {
+	from			int
+	to				int
+	ship			int
+	fleeing			bool
+	danger			bool
+	fields			[]string
+	as_json			bool
+}
+
+func (self *query) wants_turn(turn int) bool {
+	return turn >= self.from && (self.to < 0 || turn <= self.to)
+}
+
+func (self *query) wants_pilot(pt *ai.PilotTrace) bool {
+	if self.ship >= 0 && pt.Sid != self.ship {
+		return false
+	}
+	if self.fleeing && pt.Fleeing == false {
+		return false
+	}
+	if self.danger && len(pt.DangerShips) == 0 {
+		return false
+	}
+	return true
+}
+
+func (self *query) pilot_filter() bool {
+	return self.ship >= 0 || self.fleeing || self.danger
+}
+
+func main() {
+
+	var q query
+	var turn int
+	var fields string
+
+	flag.IntVar(&turn, "turn", -1, "only this turn")
+	flag.IntVar(&q.from, "from", 0, "first turn")
+	flag.IntVar(&q.to, "to", -1, "last turn (-1: no limit)")
+	flag.IntVar(&q.ship, "ship", -1, "only this ship's pilot")
+	flag.BoolVar(&q.fleeing, "fleeing", false, "only fleeing pilots")
+	flag.BoolVar(&q.danger, "danger", false, "only pilots with danger ships")
+	flag.StringVar(&fields, "fields", "", "comma separated pilot fields to show (default: a summary)")
+	flag.BoolVar(&q.as_json, "json", false, "output matching records as JSON lines")
+	flag.Parse()
+
+	if turn >= 0 {
+		q.from, q.to = turn, turn
+	}
+
+	if fields != "" {
+		q.fields = strings.Split(fields, ",")
+	}
+
+	if flag.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: trace [flags] trace.jsonl [...]\n")
+		os.Exit(1)
+	}
+
+	for _, filename := range flag.Args() {
+		err := run(filename, &q)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func run(filename string, q *query) error {
+
+	infile, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer infile.Close()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	encoder := json.NewEncoder(out)
+
+	scanner := bufio.NewScanner(infile)
+	scanner.Buffer(make([]byte, 64 * 1024), 64 * 1024 * 1024)
+
+	for line := 1; scanner.Scan(); line++ {
+
+		var tt ai.TurnTrace
+
+		err := json.Unmarshal(scanner.Bytes(), &tt)
+		if err != nil {
+			return fmt.Errorf("%s, line %d: %v", filename, line, err)
+		}
+
+		if q.wants_turn(tt.Turn) == false {
+			continue
+		}
+
+		var pilots []*ai.PilotTrace
+		for _, pt := range tt.Pilots {
+			if q.wants_pilot(pt) {
+				pilots = append(pilots, pt)
+			}
+		}
+
+		if len(pilots) == 0 && q.pilot_filter() {
+			continue
+		}
+
+		tt.Pilots = pilots
+
+		if q.as_json {
+			encoder.Encode(tt)
+			continue
+		}
+
+		fmt.Fprintf(out, "t %3d: strategy %s, %s, coward %v, avoiding bad 2v1 %v\n",
+			tt.Turn, tt.Strategy, tt.RushChoice, tt.CowardFlag, tt.AvoidingBad2v1)
+
+		for _, pt := range tt.Pilots {
+			if len(q.fields) == 0 {
+				fmt.Fprintf(out, "    ship %4d  [%6.1f, %6.1f]  %-16s  target %s\n", pt.Sid, pt.X, pt.Y, pt.Order, pt.Target)
+			} else {
+				print_fields(out, pt, q.fields)
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// print_fields shows the named fields of a pilot, going via its JSON so names match the file.
+
+func print_fields(out *bufio.Writer, pt *ai.PilotTrace, fields []string) {
+
+	b, _ := json.Marshal(pt)
+
+	var m map[string]interface{}
+	json.Unmarshal(b, &m)
+
+	fmt.Fprintf(out, "    ship %4d:", pt.Sid)
+
+	for _, field := range fields {
+
+		v, ok := m[field]
+		if ok == false {
+			fmt.Fprintf(out, "  %s: (no such field)", field)
+			continue
+		}
+
+		if list, is_list := v.([]interface{}); is_list && field == "nav_stack" {
+			fmt.Fprintf(out, "\n        nav_stack:")
+			for _, s := range list {
+				fmt.Fprintf(out, "\n            %v", s)
+			}
+			fmt.Fprintf(out, "\n   ")
+			continue
+		}
+
+		fmt.Fprintf(out, "  %s: %v", field, v)
+	}
+
+	fmt.Fprintf(out, "\n")
+}
@@ -0,0 +1,341 @@
+package main
+
+// Searches the bot's tuned parameters (core.Params) by playing local games against reference
+// opponents. Build MyBot first; then, from the bot directory:
+//
+//     tune -bot ./MyBot -opponents "../basic/MyBot,./MyBot" -games 20 -gens 10
+//
+// Each candidate is a params file handed to our bot with -params. Opponents are any bot
+// commands; an opponent of "./MyBot" is our bot with its default params (i.e. the current
+// config), and older params files can be used via "./MyBot -params old.json".
+//
+// The search is a simple GA in the spirit of the rush GA: the best half of each generation
+// survives, and the rest are replaced by copies of survivors with one parameter changed.
+// Within a generation every candidate plays the same seeds, so they're compared fairly.
+// Win rates are reported with 95% (Wilson) confidence intervals, which are usually wide -
+// treat small differences with suspicion.
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	hal "../../core"
+	eng "../../engine"
+)
+
+type dimension struct {
+	name			string			// As in the JSON of core.Params
+	min				float64
+	max				float64
+	integer			bool
+}
+
+var space = []dimension{
+	{"threat_range",		10,		30,		false},
+	{"panic_range",			15,		45,		false},
+	{"dodge_margin",		0.5,	3,		false},
+	{"ga_iterations",		2000,	30000,	true},
+	{"safe_move_range",		10,		25,		false},
+}
+
+type candidate struct {
+	values			[]float64		// One per dimension of the space
+	wins			int
+	games			int
+}
+
+func (self *candidate) Copy() *candidate {
+	return &candidate{values: append([]float64(nil), self.values...)}
+}
+
+func (self *candidate) Mutate(rng *rand.Rand) {
+
+	i := rng.Intn(len(space))
+	d := space[i]
+
+	if rng.Intn(4) == 0 {
+		self.values[i] = d.min + rng.Float64() * (d.max - d.min)
+	} else {
+		self.values[i] += rng.NormFloat64() * (d.max - d.min) / 10
+	}
+
+	self.values[i] = math.Max(d.min, math.Min(d.max, self.values[i]))
+
+	if d.integer {
+		self.values[i] = math.Round(self.values[i])
+	}
+}
+
+func (self *candidate) JSON() []byte {
+	m := make(map[string]float64)
+	for i, d := range space {
+		m[d.name] = self.values[i]
+	}
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// candidate_from_params puts the starting point into the search space.
+
+func candidate_from_params(params hal.Params) *candidate {
+
+	b, _ := json.Marshal(params)
+
+	var m map[string]float64
+	json.Unmarshal(b, &m)
+
+	ret := new(candidate)
+	for _, d := range space {
+		ret.values = append(ret.values, m[d.name])
+	}
+	return ret
+}
+
+// wilson gives the 95% confidence interval of a win rate.
+
+func wilson(wins, games int) (float64, float64) {
+
+	if games == 0 {
+		return 0, 1
+	}
+
+	const z = 1.96
+
+	n := float64(games)
+	p := float64(wins) / n
+
+	centre := (p + z * z / (2 * n)) / (1 + z * z / n)
+	spread := z * math.Sqrt(p * (1 - p) / n + z * z / (4 * n * n)) / (1 + z * z / n)
+
+	return math.Max(0, centre - spread), math.Min(1, centre + spread)
+}
+
+// --------------------------------------------
+
+type tuner struct {
+	bot				string
+	opponents		[]string
+	players			int
+	games			int
+	parallel		int
+	turn_timeout	time.Duration
+	dir				string			// Where candidates' params files go
+}
+
+// play runs one game of the candidate (in a seat that depends on g) against opponents,
+// and reports whether it won. Games run in parallel, so each gets its own working directory
+// for the bots' log files.
+
+func (self *tuner) play(params_file string, seed int64, g int) (bool, error) {
+
+	seat := g % self.players
+
+	work_dir, err := ioutil.TempDir("", "tune_game")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(work_dir)
+
+	var bots []eng.Bot
+
+	for pid := 0; pid < self.players; pid++ {
+
+		command := self.opponents[(g + pid) % len(self.opponents)]
+		if pid == seat {
+			command = fmt.Sprintf("%s -params %s", self.bot, params_file)
+		}
+
+		bot, err := eng.NewProcessBotIn(command, work_dir)
+		if err != nil {
+			for _, b := range bots {
+				b.Close()
+			}
+			return false, fmt.Errorf("couldn't start \"%s\": %v", command, err)
+		}
+		bots = append(bots, bot)
+	}
+
+	config := eng.DefaultMatchConfig(seed)
+	config.TurnTimeout = self.turn_timeout
+
+	result, err := eng.RunMatch(bots, config)
+	if err != nil {
+		return false, err
+	}
+
+	return result.Winner() == seat, nil
+}
+
+// evaluate plays every candidate's games, self.parallel games at a time.
+
+func (self *tuner) evaluate(population []*candidate, gen int, base_seed int64) error {
+
+	type job struct {
+		c				*candidate
+		params_file		string
+		g				int
+	}
+
+	jobs := make(chan job)
+	var mutex sync.Mutex
+	var failure error
+	var wg sync.WaitGroup
+
+	for w := 0; w < self.parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				won, err := self.play(j.params_file, base_seed + int64(j.g), j.g)
+				mutex.Lock()
+				if err != nil {
+					failure = err
+				} else {
+					j.c.games++
+					if won {
+						j.c.wins++
+					}
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for i, c := range population {
+
+		c.wins, c.games = 0, 0
+
+		params_file := filepath.Join(self.dir, fmt.Sprintf("gen%d_%d.json", gen, i))
+		err := ioutil.WriteFile(params_file, c.JSON(), 0644)
+		if err != nil {
+			close(jobs)
+			wg.Wait()
+			return err
+		}
+
+		for g := 0; g < self.games; g++ {
+			jobs <- job{c, params_file, g}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return failure
+}
+
+// --------------------------------------------
+
+func main() {
+
+	var t tuner
+	var opponents, init_file, out_file string
+	var pop_size, gens, turn_ms int
+	var seed int64
+
+	flag.StringVar(&t.bot, "bot", "./MyBot", "our bot's executable")
+	flag.StringVar(&opponents, "opponents", "../basic/MyBot,./MyBot", "comma separated opponent commands, used in rotation")
+	flag.IntVar(&t.players, "players", 2, "players per game (2 or 4)")
+	flag.IntVar(&t.games, "games", 20, "games per candidate per generation")
+	flag.IntVar(&t.parallel, "parallel", 2, "games played at once")
+	flag.IntVar(&turn_ms, "turntime", 2000, "time allowed per turn, in milliseconds")
+	flag.IntVar(&pop_size, "pop", 8, "candidates per generation")
+	flag.IntVar(&gens, "gens", 10, "generations")
+	flag.Int64Var(&seed, "seed", 1, "seed for the search and the maps")
+	flag.StringVar(&init_file, "init", "", "params file to start from (default: the bot's defaults)")
+	flag.StringVar(&out_file, "out", "tuned.json", "where to write the best params")
+	flag.Parse()
+
+	if t.players != 2 && t.players != 4 {
+		fmt.Fprintf(os.Stderr, "-players must be 2 or 4\n")
+		os.Exit(1)
+	}
+
+	t.opponents = strings.Split(opponents, ",")
+	t.turn_timeout = time.Duration(turn_ms) * time.Millisecond
+	t.parallel = hal.Max(1, t.parallel)
+	pop_size = hal.Max(2, pop_size)
+	gens = hal.Max(1, gens)
+
+	var err error
+
+	t.dir, err = ioutil.TempDir("", "tune")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(t.dir)
+
+	start := hal.DefaultParams()
+	if init_file != "" {
+		start, err = hal.LoadParams(init_file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+
+	population := []*candidate{candidate_from_params(start)}		// The starting point itself is kept as a baseline.
+
+	for len(population) < pop_size {
+		c := population[0].Copy()
+		c.Mutate(rng)
+		population = append(population, c)
+	}
+
+	var best *candidate
+
+	for gen := 0; gen < gens; gen++ {
+
+		err := t.evaluate(population, gen, seed * 100000 + int64(gen) * 1000)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+
+		sort.SliceStable(population, func(a, b int) bool {
+			return population[a].wins > population[b].wins
+		})
+
+		fmt.Printf("Generation %d\n", gen)
+
+		for _, c := range population {
+			lo, hi := wilson(c.wins, c.games)
+			fmt.Printf("  %3d / %3d  %5.1f%%  [%5.1f%%, %5.1f%%]  %s\n",
+				c.wins, c.games, 100 * float64(c.wins) / float64(c.games), 100 * lo, 100 * hi, c.JSON())
+		}
+
+		best = population[0]
+
+		err = ioutil.WriteFile(out_file, best.JSON(), 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+
+		// Keep the best half, refill with mutants of them...
+
+		survivors := population[:(pop_size + 1) / 2]
+		population = append([]*candidate(nil), survivors...)
+
+		for i := 0; len(population) < pop_size; i++ {
+			c := survivors[i % len(survivors)].Copy()
+			c.Mutate(rng)
+			population = append(population, c)
+		}
+	}
+
+	fmt.Printf("Best params written to %s: %s\n", out_file, best.JSON())
+}
@@ -0,0 +1,167 @@
+package main
+
+// Draws replay frames as SVG (or text), with our AI's per-pilot view on top: targets, nav
+// waypoints and the opening docks. The overlay comes from running Overmind.Step() over the
+// replay as the given player, as cmd/golden does. From the bot directory:
+//
+//     viz -turn 40 "../reference replays/v99 - chase chase.hlt"             writes turn_040.svg
+//     viz -turn 10 -to 30 -out "chase_%03d.svg" "../reference replays/v99 - chase chase.hlt"
+//     viz -term -turn 40 "../reference replays/v99 - chase chase.hlt"
+//
+// Hover over a ship in the SVG to see its pilot's order and nav stack.
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	ai "../../ai"
+	nav "../../navigation"
+	rep "../../replay"
+	viz "../../viz"
+)
+
+func main() {
+
+	var first, last, pid, cols int
+	var out_pattern string
+	var term, no_overlay, no_weapons, no_threats bool
+
+	opts := viz.DefaultOptions()
+	config := new(ai.Config)
+
+	flag.IntVar(&first, "turn", 0, "turn to draw")
+	flag.IntVar(&last, "to", -1, "draw every turn up to this one too")
+	flag.IntVar(&pid, "pid", -1, "player whose AI is overlaid (default: find fohristiwhirl)")
+	flag.StringVar(&out_pattern, "out", "turn_%03d.svg", "output filename, with a %d for the turn")
+	flag.BoolVar(&term, "term", false, "print to the terminal instead of writing SVG")
+	flag.IntVar(&cols, "cols", 120, "width of the terminal drawing")
+	flag.Float64Var(&opts.Scale, "scale", opts.Scale, "SVG pixels per game unit")
+	flag.BoolVar(&no_overlay, "nooverlay", false, "just draw the replay")
+	flag.BoolVar(&no_weapons, "noweapons", false, "don't draw weapon ranges")
+	flag.BoolVar(&no_threats, "nothreats", false, "don't draw planet threat ranges")
+	flag.BoolVar(&config.Hungarian, "hungarian", false, "overlay AI: optimal (min-cost) target assignment")
+	flag.StringVar(&config.ParamsFile, "params", "", "overlay AI: JSON file of tuned parameters")
+	flag.Parse()
+
+	opts.Weapons = no_weapons == false
+	opts.Threats = no_threats == false
+
+	if flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: viz [flags] replay.hlt\n")
+		os.Exit(1)
+	}
+
+	if last < first {
+		last = first
+	}
+
+	replay, err := rep.Load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if last >= len(replay.Frames) {
+		fmt.Fprintf(os.Stderr, "Replay only has %d frames\n", len(replay.Frames))
+		os.Exit(1)
+	}
+
+	overlays := make(map[int]*viz.Overlay)
+
+	if no_overlay == false {
+
+		if pid == -1 {
+			for i, name := range replay.PlayerNames {
+				if strings.HasPrefix(strings.ToLower(name), "fohristiwhirl") {
+					pid = i
+				}
+			}
+		}
+
+		if pid == -1 {
+			fmt.Fprintf(os.Stderr, "No -pid given and couldn't find our own player in %v\n", replay.PlayerNames)
+			os.Exit(1)
+		}
+
+		overlays, err = run_overlays(replay, pid, first, last, config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	for turn := first; turn <= last; turn++ {
+
+		if term {
+			err = viz.Terminal(os.Stdout, replay, turn, overlays[turn], cols)
+		} else {
+			err = write_svg(fmt.Sprintf(out_pattern, turn), replay, turn, overlays[turn], opts)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// run_overlays plays the replay through the Overmind up to the last turn wanted, since its
+// state (targets, rush choice, etc) depends on every earlier turn.
+
+func run_overlays(replay *rep.Replay, pid, first, last int, config *ai.Config) (ret map[int]*viz.Overlay, err error) {
+
+	nav.Ignore_Collision_Dist = 100				// As per MyBot's default flags
+
+	playback, err := rep.NewPlayback(replay, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	game := playback.Game()
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic on turn %d: %v", game.Turn(), p)
+		}
+	}()
+
+	ret = make(map[int]*viz.Overlay)
+	overmind := ai.NewOvermind(game, config)
+
+	for turn := 0; turn <= last; turn++ {
+
+		err = playback.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		rand.Seed(int64(game.Turn() + game.Width() + game.Pid()))		// As MyBot does
+
+		overmind.Step()
+
+		if turn >= first {
+			ret[turn] = viz.NewOverlay(overmind)
+		}
+	}
+
+	return ret, nil
+}
+
+func write_svg(filename string, replay *rep.Replay, turn int, overlay *viz.Overlay, opts viz.Options) error {
+
+	outfile, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = viz.SVG(outfile, replay, turn, overlay, opts)
+	if err != nil {
+		outfile.Close()
+		return err
+	}
+
+	return outfile.Close()
+}
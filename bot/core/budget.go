@@ -0,0 +1,50 @@
+package core
+
+import (
+	"math"
+	"time"
+)
+
+// Budget is the wall-clock time allowed for our turn, counted from the moment Parse() got the
+// first token of the frame (i.e. when the engine's clock started). Expensive phases (the GA,
+// target optimisation, navigation) consult it and settle for the best they have so far once
+// their share of it is used up.
+
+const (
+	DEFAULT_BUDGET = 1500 * time.Millisecond		// The engine allows 2 seconds; leave room for everything else.
+)
+
+type Budget struct {
+	start			time.Time
+	limit			time.Duration					// <= 0 means unlimited
+}
+
+func (self *Budget) Start(t time.Time) { self.start = t }
+func (self *Budget) Limit() time.Duration { return self.limit }
+func (self *Budget) SetLimit(d time.Duration) { self.limit = d }
+func (self *Budget) Elapsed() time.Duration { return time.Now().Sub(self.start) }
+
+func (self *Budget) Remaining() time.Duration {
+	if self.limit <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return self.limit - self.Elapsed()
+}
+
+func (self *Budget) Expired() bool {
+	return self.Used(1.0)
+}
+
+// Used reports whether the given fraction of the budget has gone, so that a phase which
+// isn't the last can leave time for those after it.
+
+func (self *Budget) Used(fraction float64) bool {
+	if self.limit <= 0 {
+		return false
+	}
+	return self.Elapsed() > time.Duration(float64(self.limit) * fraction)
+}
+
+func (self *Game) Budget() *Budget {
+	return &self.budget
+}
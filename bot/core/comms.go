@@ -3,6 +3,7 @@ package core
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"sort"
@@ -17,30 +18,95 @@ type TokenParser struct {
 	scanner		*bufio.Scanner
 	count		int
 	all_tokens	[]string		// This is used for logging only. It is cleared each time it's asked-for.
+	context		string			// What we're reading, for error messages, e.g. "ship 42 of player 1"
+	err			*ParseError		// The first error. After it, every read fails and returns 0.
+}
+
+// A ParseError says where the input went wrong. Token is the index within the current frame
+// (the init message's header counts as a frame of its own).
+
+type ParseError struct {
+	Token		int
+	Expected	string
+	Got			string			// "" at end of input
+	Context		string
+	Err			error			// The scanner's error, if that's what went wrong
+}
+
+func (self *ParseError) Error() string {
+
+	got := fmt.Sprintf("\"%s\"", self.Got)
+	if self.Err != nil {
+		got = fmt.Sprintf("read error: %v", self.Err)
+	} else if self.Got == "" {
+		got = "end of input"
+	}
+
+	return fmt.Sprintf("token %d (%s): expected %s, got %s", self.Token, self.Context, self.Expected, got)
 }
 
 func NewTokenParser() *TokenParser {
+	return NewTokenParserFromReader(os.Stdin)
+}
+
+func NewTokenParserFromReader(r io.Reader) *TokenParser {
 	ret := new(TokenParser)
-	ret.scanner = bufio.NewScanner(os.Stdin)
+	ret.scanner = bufio.NewScanner(r)
 	ret.scanner.Split(bufio.ScanWords)
 	return ret
 }
 
-func (self *TokenParser) Int() int {
-	bl := self.scanner.Scan()
-	if bl == false {
-		err := self.scanner.Err()
-		if err != nil {
-			panic(fmt.Sprintf("%v", err))
-		} else {
-			panic(fmt.Sprintf("End of input."))
+// Context sets the description of what's being read, used by any ParseError that follows.
+
+func (self *TokenParser) Context(format_string string, args ...interface{}) {
+	self.context = fmt.Sprintf(format_string, args...)
+}
+
+// Err returns the first error, if any, as an error (not as a nil *ParseError).
+
+func (self *TokenParser) Err() error {
+	if self.err == nil {
+		return nil
+	}
+	return self.err
+}
+
+func (self *TokenParser) fail(expected, got string, err error) {
+	if self.err == nil {
+		self.err = &ParseError{
+			Token: len(self.all_tokens),
+			Expected: expected,
+			Got: got,
+			Context: self.context,
+			Err: err,
 		}
 	}
-	self.all_tokens = append(self.all_tokens, self.scanner.Text())
-	ret, err := strconv.Atoi(self.scanner.Text())
+}
+
+// next returns the next token, or ok == false (with the error recorded) at end of input.
+
+func (self *TokenParser) next(expected string) (string, bool) {
+	if self.err != nil {
+		return "", false
+	}
+	if self.scanner.Scan() == false {
+		self.fail(expected, "", self.scanner.Err())
+		return "", false
+	}
+	return self.scanner.Text(), true
+}
+
+func (self *TokenParser) Int() int {
+	token, ok := self.next("int")
+	if ok == false {
+		return 0
+	}
+	ret, err := strconv.Atoi(token)
 	if err != nil {
-		panic(fmt.Sprintf("TokenReader.Int(): Atoi failed at token %d: \"%s\"", self.count, self.scanner.Text()))
+		self.fail("int", token, nil)
+		return 0
 	}
+	self.all_tokens = append(self.all_tokens, token)
 	self.count++
 	return ret
 }
@@ -50,30 +116,32 @@ func (self *TokenParser) DockedStatus() DockedStatus {
 }
 
 func (self *TokenParser) Float() float64 {
-	bl := self.scanner.Scan()
-	if bl == false {
-		err := self.scanner.Err()
-		if err != nil {
-			panic(fmt.Sprintf("%v", err))
-		} else {
-			panic(fmt.Sprintf("End of input."))
-		}
+	token, ok := self.next("float")
+	if ok == false {
+		return 0
 	}
-	self.all_tokens = append(self.all_tokens, self.scanner.Text())
-	ret, err := strconv.ParseFloat(self.scanner.Text(), 64)
+	ret, err := strconv.ParseFloat(token, 64)
 	if err != nil {
-		panic(fmt.Sprintf("TokenReader.Float(): ParseFloat failed at token %d: \"%s\"", self.count, self.scanner.Text()))
+		self.fail("float", token, nil)
+		return 0
 	}
+	self.all_tokens = append(self.all_tokens, token)
 	self.count++
 	return ret
 }
 
 func (self *TokenParser) Bool() bool {
-	val := self.Int()
-	if val != 0 && val != 1 {
-		panic(fmt.Sprintf("TokenReader.Bool(): Value wasn't 0 or 1 (was: \"%d\")", val))
+	token, ok := self.next("bool")
+	if ok == false {
+		return false
 	}
-	return val == 1
+	if token != "0" && token != "1" {
+		self.fail("bool (0 or 1)", token, nil)
+		return false
+	}
+	self.all_tokens = append(self.all_tokens, token)
+	self.count++
+	return token == "1"
 }
 
 func (self *TokenParser) Tokens(sep string) string {
@@ -88,16 +156,24 @@ func (self *TokenParser) ClearTokens() {
 
 // ---------------------------------------
 
-func (self *Game) Parse() {
+// Parse reads the next frame. If the input is malformed or ends early, the error is a *ParseError
+// saying where. Failing on the very first token (e.g. at the end of the game) leaves our state as it
+// was; anything later leaves it half-updated, so the Game shouldn't be used after that.
 
-	// Do our first read before clearing things, so that it panics on EOF and we haven't corrupted our state...
+func (self *Game) Parse() error {
 
+	// Do our first read before clearing things, so that on EOF we haven't corrupted our state...
+
+	self.token_parser.Context("player count")
 	player_count := self.token_parser.Int()
 
+	if err := self.token_parser.Err(); err != nil {
+		return err
+	}
+
 	// Now reset various things...
 
-	self.orders = make(map[int]string)			// Clear all orders.
-	self.messages = make(map[int]int)
+	self.ClearOrders()
 
 	if self.inited {
 		self.turn++
@@ -130,6 +206,7 @@ func (self *Game) Parse() {
 	// Player parsing.............................................................................
 
 	self.parse_time = time.Now()				// MUST happen AFTER the first token parse. <------------------------------------- important
+	self.budget.Start(self.parse_time)
 
 	if self.initialPlayers == 0 {
 		self.initialPlayers = player_count		// Only save this at init stage.
@@ -139,8 +216,10 @@ func (self *Game) Parse() {
 
 	for p := 0; p < player_count; p++ {
 
+		self.token_parser.Context("player index %d", p)
 		pid := self.token_parser.Int()
 
+		self.token_parser.Context("ship count of player %d", pid)
 		ship_count := self.token_parser.Int()
 
 		if ship_count > 0 {
@@ -149,8 +228,15 @@ func (self *Game) Parse() {
 
 		for s := 0; s < ship_count; s++ {
 
+			self.token_parser.Context("ship index %d of player %d", s, pid)
 			sid := self.token_parser.Int()
 
+			if err := self.token_parser.Err(); err != nil {
+				return err
+			}
+
+			self.token_parser.Context("ship %d of player %d", sid, pid)
+
 			ship, ok := old_shipmap[sid]
 
 			// Save the previous state of the ship, if any...
@@ -184,6 +270,10 @@ func (self *Game) Parse() {
 
 			self.token_parser.Int()									// Skip deprecated "cooldown"
 
+			if err := self.token_parser.Err(); err != nil {
+				return err
+			}
+
 			if last_ship == nil {
 				ship.Birth = Max(0, self.turn)						// Turn can be -1 in init stage.
 				ship.SpawnX = ship.X
@@ -218,12 +308,24 @@ func (self *Game) Parse() {
 
 	// Planet parsing.............................................................................
 
+	self.token_parser.Context("planet count")
 	planet_count := self.token_parser.Int()
 
+	if err := self.token_parser.Err(); err != nil {
+		return err
+	}
+
 	for p := 0; p < planet_count; p++ {
 
+		self.token_parser.Context("planet index %d", p)
 		plid := self.token_parser.Int()
 
+		if err := self.token_parser.Err(); err != nil {
+			return err
+		}
+
+		self.token_parser.Context("planet %d", plid)
+
 		planet, ok := old_planetmap[plid]
 		if ok == false {
 			planet = new(Planet)
@@ -248,6 +350,10 @@ func (self *Game) Parse() {
 		}
 
 		planet.DockedShips = self.token_parser.Int()
+
+		if err := self.token_parser.Err(); err != nil {
+			return err
+		}
 		official_docked_ships := planet.DockedShips					// How many ships the engine will send us, regardless of truth...
 
 		// The dockMap is kept separately due to an old design decision...
@@ -257,10 +363,16 @@ func (self *Game) Parse() {
 
 			// This relies on the fact that we've already been given info about the ships...
 
+			self.token_parser.Context("docked ship index %d of planet %d", s, plid)
 			sid := self.token_parser.Int()
+
+			if err := self.token_parser.Err(); err != nil {
+				return err
+			}
+
 			ship, ok := self.GetShip(sid)
 			if ok == false {
-				panic("Parser choked on GetShip(sid)")
+				return fmt.Errorf("planet %d: docked ship %d is not in the frame", plid, sid)
 			}
 
 			if ship.DockedStatus != UNDOCKED {								// Can be false due to fudge_dock_status()
@@ -334,6 +446,14 @@ func (self *Game) Parse() {
 		return self.all_immobile_cache[a].GetId() < self.all_immobile_cache[b].GetId()
 	})
 
+	self.grid = NewGrid(self.width, self.height)
+	for _, ship := range self.all_ships_cache {
+		self.grid.AddShip(ship)
+	}
+	for _, planet := range self.all_planets_cache {
+		self.grid.AddPlanet(planet)
+	}
+
 	// Some meta info...
 
 	self.currentPlayers = players_with_ships
@@ -350,43 +470,17 @@ func (self *Game) Parse() {
 	self.UpdateFriendMap()
 	self.PredictTimeZero()
 	self.UpdateShipNearestEnemies()
-}
-
-// ---------------------------------------
 
-func (self *Game) Thrust(ship *Ship, speed, degrees int) {
-	for degrees < 0 { degrees += 360 }; degrees %= 360
-	self.orders[ship.Id] = fmt.Sprintf("t %d %d %d", ship.Id, speed, degrees)
-}
-
-func (self *Game) SetMessage(ship *Ship, message int) {
-	if message < 0 || message > 180 {
-		return
-	}
-	self.messages[ship.Id] = message
-}
+	self.session.Record("frame", "%d %s", self.turn, self.raw)
 
-func (self *Game) Dock(ship *Ship, planet Planet) {
-	self.orders[ship.Id] = fmt.Sprintf("d %d %d", ship.Id, planet.Id)
+	return nil
 }
 
-func (self *Game) Undock(ship *Ship) {
-	self.orders[ship.Id] = fmt.Sprintf("u %d", ship.Id)
-}
-
-func (self *Game) ClearOrder(ship *Ship) {
-	delete(self.orders, ship.Id)
-}
-
-func (self *Game) CurrentOrder(ship *Ship) string {
-	return self.orders[ship.Id]
-}
-
-func (self *Game) RawOrder(sid int, s string) {
-	self.orders[sid] = s
-}
+// ---------------------------------------
 
 func (self *Game) Send(no_messages bool) {
-	fmt.Printf(self.RawOutput(false, no_messages))
-	fmt.Printf("\n")
+	self.ValidateOrders(false)									// Repairs (and logs) anything illegal.
+	out := self.RawOutput(false, no_messages)
+	fmt.Fprintf(self.writer, "%s\n", out)
+	self.session.Record("sent", "%d %s", self.turn, out)
 }
@@ -124,25 +124,9 @@ func (s *Ship) VagueDirection() Edge {
 }
 
 func (s *Ship) find_closest_enemy(game *Game) *Ship {
-
-	var ret *Ship
-
-	closest_dist := 9999.9
-
-	for _, other := range game.AllShips() {
-
-		if other.Owner == s.Owner {
-			continue
-		}
-
-		d := s.Dist(other)
-		if d < closest_dist {
-			ret = other
-			closest_dist = d
-		}
-	}
-
-	return ret
+	return game.grid.NearestShip(s.X, s.Y, func(other *Ship) bool {
+		return other.Owner != s.Owner
+	})
 }
 
 func (s *Ship) fudge_dock_status() {
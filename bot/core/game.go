@@ -2,6 +2,9 @@ package core
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"sort"
 	"strings"
 	"time"
@@ -46,15 +49,19 @@ type Game struct {
 	cumulativeShips				map[int]int			// Player ID --> Count
 	lastownerMap				map[int]int			// Planet ID --> Last owner (check OK for never owned)
 
-	orders						map[int]string
-	messages					map[int]int			// For the Chlorine viewer
+	orders						map[int]Order
 
 	logfile						*Logfile
+	tracefile					*Tracefile		// nil unless tracing (see trace.go)
+	session						*Sessionfile	// nil unless recording (see session.go)
 	token_parser				*TokenParser
+	writer						io.Writer
 	raw							string
 	run_of_sames				int
 
 	parse_time					time.Time
+	budget						Budget
+	params						Params				// Tuned numbers, see params.go
 
 	// These slices are kept as answers to common queries...
 
@@ -62,6 +69,7 @@ type Game struct {
 	enemy_ships_cache			[]*Ship
 	all_planets_cache			[]*Planet
 	all_immobile_cache			[]Entity			// Planets and docked ships
+	grid						*Grid				// Spatial index of the above
 
 	// Some more stuff maybe used by the AI...
 
@@ -73,12 +81,44 @@ type Game struct {
 }
 
 func NewGame() *Game {
+	return NewGameFromIO(os.Stdin, os.Stdout)
+}
+
+// NewGameFromReader reads the init message (and all later frames) from r instead of stdin.
+// The reader is only read from when Parse() needs more tokens, so frames can be supplied
+// one at a time, e.g. by appending them to a bytes.Buffer before each Parse(). Send() output
+// is discarded.
+
+func NewGameFromReader(r io.Reader) *Game {
+	return NewGameFromIO(r, ioutil.Discard)
+}
+
+// NewGameFromIO reads frames from r and sends orders to w. It panics if the init message is
+// bad; bots running in one process (see engine.NewInProcessBot) should use ReadGame instead.
+
+func NewGameFromIO(r io.Reader, w io.Writer) *Game {
+	game, err := ReadGame(r, w)
+	if err != nil {
+		panic(fmt.Sprintf("NewGameFromIO(): %v", err))
+	}
+	return game
+}
+
+// ReadGame is NewGameFromIO but returns an error (usually a *ParseError) if the init message is bad.
+
+func ReadGame(r io.Reader, w io.Writer) (*Game, error) {
 	game := new(Game)
 	game.turn = -1
-	game.token_parser = NewTokenParser()
+	game.token_parser = NewTokenParserFromReader(r)
+	game.writer = w
+	game.token_parser.Context("player ID")
 	game.pid = game.token_parser.Int()
+	game.token_parser.Context("map size")
 	game.width = game.token_parser.Int()
 	game.height = game.token_parser.Int()
+	if err := game.token_parser.Err(); err != nil {
+		return nil, err
+	}
 	game.planetMap = make(map[int]*Planet)
 	game.shipMap = make(map[int]*Ship)
 	game.dockMap = make(map[int][]*Ship)
@@ -86,10 +126,15 @@ func NewGame() *Game {
 	game.lastownerMap = make(map[int]int)
 	game.threat_range = INITIAL_THREAT_RANGE
 	game.friend_range = INITIAL_FRIEND_RANGE
+	game.budget.SetLimit(DEFAULT_BUDGET)
+	game.params = DefaultParams()
 	game.token_parser.ClearTokens()				// This is just clearing the token_parser's "log".
-	game.Parse()
+	err := game.Parse()
+	if err != nil {
+		return nil, err
+	}
 	game.inited = true		// Just means Parse() will increment the turn value before parsing.
-	return game
+	return game, nil
 }
 
 func (self *Game) Turn() int { return self.turn }
@@ -106,25 +151,25 @@ func (self *Game) UpdateEnemyMaps() {
 	self.enemies_near_planet = make(map[int][]*Ship)
 	self.mobile_enemies_near_planet = make(map[int][]*Ship)
 
-	all_ships := self.AllShips()
-	all_planets := self.AllPlanets()
+	for _, planet := range self.AllPlanets() {
 
-	for _, ship := range all_ships {
-		if ship.Owner != self.Pid() {
-			for _, planet := range all_planets {
-				if ship.ApproachDist(planet) < self.threat_range {
+		// The grid query is a little generous, the exact test is below. Its results come in ID order,
+		// as the old scan over AllShips() did.
 
-					// enemies_near_planet includes all mobile enemies, plus enemies docked at the planet...
+		for _, ship := range self.grid.ShipsNear(planet.X, planet.Y, self.threat_range + planet.Radius + 0.001) {
 
-					if ship.CanMove() || ship.DockedPlanet == planet.Id {
-						self.enemies_near_planet[planet.Id] = append(self.enemies_near_planet[planet.Id], ship)
-					}
+			if ship.Owner != self.Pid() && ship.ApproachDist(planet) < self.threat_range {
 
-					// mobile_enemies_near_planet only includes mobile enemies...
+				// enemies_near_planet includes all mobile enemies, plus enemies docked at the planet...
 
-					if ship.CanMove() {
-						self.mobile_enemies_near_planet[planet.Id] = append(self.mobile_enemies_near_planet[planet.Id], ship)
-					}
+				if ship.CanMove() || ship.DockedPlanet == planet.Id {
+					self.enemies_near_planet[planet.Id] = append(self.enemies_near_planet[planet.Id], ship)
+				}
+
+				// mobile_enemies_near_planet only includes mobile enemies...
+
+				if ship.CanMove() {
+					self.mobile_enemies_near_planet[planet.Id] = append(self.mobile_enemies_near_planet[planet.Id], ship)
 				}
 			}
 		}
@@ -135,12 +180,9 @@ func (self *Game) UpdateFriendMap() {
 
 	self.friends_near_planet = make(map[int][]*Ship)
 
-	my_ships := self.MyShips()
-	all_planets := self.AllPlanets()
-
-	for _, ship := range my_ships {
-		for _, planet := range all_planets {
-			if ship.CanMove() {
+	for _, planet := range self.AllPlanets() {
+		for _, ship := range self.grid.ShipsNear(planet.X, planet.Y, self.friend_range + planet.Radius + 0.001) {
+			if ship.Owner == self.Pid() && ship.CanMove() {
 				if ship.ApproachDist(planet) < self.friend_range {
 					self.friends_near_planet[planet.Id] = append(self.friends_near_planet[planet.Id], ship)
 				}
@@ -171,30 +213,8 @@ func (self *Game) RawOutput(sorted, no_messages bool) string {
 
 	var commands []string
 
-	for sid, s := range self.orders {
-
-		if s != "" {
-
-			message, ok := self.messages[sid]
-
-			if no_messages == false && s[0] == 't' && ok {
-
-				speed, degrees := CourseFromString(s)
-
-				// We put some extra info into the angle, which we can see in the Chlorine replayer...
-
-				if message >= 0 && message <= 180 {
-					degrees += (int(message) + 1) * 360
-				}
-
-				commands = append(commands, fmt.Sprintf("t %d %d %d", sid, speed, degrees))
-
-			} else {
-
-				commands = append(commands, s)
-
-			}
-		}
+	for _, order := range self.orders {
+		commands = append(commands, order.Wire(no_messages))
 	}
 
 	if sorted {
@@ -216,15 +236,11 @@ func (self *Game) PredictTimeZero() {
 
 	all_ships := self.AllShips()
 
-	for i := 0; i < len(all_ships); i++ {
-
-		ship1 := all_ships[i]
-
-		for k := i + 1; k < len(all_ships); k++ {
+	for _, ship1 := range all_ships {
 
-			ship2 := all_ships[k]
+		for _, ship2 := range self.grid.ShipsNear(ship1.X, ship1.Y, WEAPON_RANGE + SHIP_RADIUS * 2 + 0.001) {
 
-			if ship1.Owner == ship2.Owner {
+			if ship2.Id <= ship1.Id || ship1.Owner == ship2.Owner {			// Each pair once
 				continue
 			}
 
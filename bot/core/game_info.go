@@ -127,6 +127,10 @@ func (self *Game) ShipsDockedAt(planet *Planet) []*Ship {
 
 func (self *Game) ClosestPlanet(e Entity) *Planet {
 
+	if e.Type() != NOTHING {
+		return self.grid.NearestPlanet(e.GetX(), e.GetY())
+	}
+
 	var best_dist float64 = 9999999
 	var ret *Planet
 
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"math"
+	"sort"
+)
+
+// Grid is a uniform grid over the map, rebuilt by every Parse(), so that "what's near here?"
+// doesn't mean a scan of every ship. Ships are filed under the cell holding their centre;
+// planets under every cell their bounding square touches.
+//
+// Results are the same as the old full scans would give, including ties: lists come sorted
+// by ID, and the Nearest functions prefer the lowest ID among equals.
+
+const (
+	GRID_CELL = 12.0
+)
+
+type Grid struct {
+	cols			int
+	rows			int
+	ships			[][]*Ship
+	planets			[][]*Planet
+}
+
+func NewGrid(width, height int) *Grid {
+	ret := new(Grid)
+	ret.cols = int(math.Ceil(float64(width) / GRID_CELL)) + 1
+	ret.rows = int(math.Ceil(float64(height) / GRID_CELL)) + 1
+	ret.ships = make([][]*Ship, ret.cols * ret.rows)
+	ret.planets = make([][]*Planet, ret.cols * ret.rows)
+	return ret
+}
+
+func (self *Game) Grid() *Grid {
+	return self.grid
+}
+
+func (self *Grid) cell(x, y float64) (int, int) {
+	i := Max(0, Min(self.cols - 1, int(math.Floor(x / GRID_CELL))))
+	j := Max(0, Min(self.rows - 1, int(math.Floor(y / GRID_CELL))))
+	return i, j
+}
+
+func (self *Grid) AddShip(ship *Ship) {
+	i, j := self.cell(ship.X, ship.Y)
+	self.ships[j * self.cols + i] = append(self.ships[j * self.cols + i], ship)
+}
+
+func (self *Grid) AddPlanet(planet *Planet) {
+	i1, j1 := self.cell(planet.X - planet.Radius, planet.Y - planet.Radius)
+	i2, j2 := self.cell(planet.X + planet.Radius, planet.Y + planet.Radius)
+	for j := j1; j <= j2; j++ {
+		for i := i1; i <= i2; i++ {
+			self.planets[j * self.cols + i] = append(self.planets[j * self.cols + i], planet)
+		}
+	}
+}
+
+// ShipsNear returns the ships whose centres are within r of (x, y), sorted by ID.
+
+func (self *Grid) ShipsNear(x, y, r float64) []*Ship {
+
+	var ret []*Ship
+
+	i1, j1 := self.cell(x - r, y - r)
+	i2, j2 := self.cell(x + r, y + r)
+
+	for j := j1; j <= j2; j++ {
+		for i := i1; i <= i2; i++ {
+			for _, ship := range self.ships[j * self.cols + i] {
+				if Dist(x, y, ship.X, ship.Y) <= r {
+					ret = append(ret, ship)
+				}
+			}
+		}
+	}
+
+	sort.Slice(ret, func(a, b int) bool {
+		return ret[a].Id < ret[b].Id
+	})
+
+	return ret
+}
+
+// PlanetsNear returns the planets whose surfaces are within r of (x, y), sorted by ID.
+
+func (self *Grid) PlanetsNear(x, y, r float64) []*Planet {
+
+	var ret []*Planet
+	seen := make(map[int]bool)
+
+	i1, j1 := self.cell(x - r, y - r)
+	i2, j2 := self.cell(x + r, y + r)
+
+	for j := j1; j <= j2; j++ {
+		for i := i1; i <= i2; i++ {
+			for _, planet := range self.planets[j * self.cols + i] {
+				if seen[planet.Id] == false && Dist(x, y, planet.X, planet.Y) - planet.Radius <= r {
+					seen[planet.Id] = true
+					ret = append(ret, planet)
+				}
+			}
+		}
+	}
+
+	sort.Slice(ret, func(a, b int) bool {
+		return ret[a].Id < ret[b].Id
+	})
+
+	return ret
+}
+
+// NearestShip returns the ship closest (centre to centre) to (x, y) for which accept() is
+// true, or nil. accept can be nil, meaning any ship.
+
+func (self *Grid) NearestShip(x, y float64, accept func(*Ship) bool) *Ship {
+
+	var ret *Ship
+	best_dist := math.Inf(1)
+
+	self.search_rings(x, y, func(index int) {
+		for _, ship := range self.ships[index] {
+			if accept != nil && accept(ship) == false {
+				continue
+			}
+			d := Dist(x, y, ship.X, ship.Y)
+			if d < best_dist || (d == best_dist && ship.Id < ret.Id) {
+				ret, best_dist = ship, d
+			}
+		}
+	}, func() float64 { return best_dist })
+
+	return ret
+}
+
+// NearestPlanet returns the planet whose surface is closest to (x, y), or nil.
+
+func (self *Grid) NearestPlanet(x, y float64) *Planet {
+
+	var ret *Planet
+	best_dist := math.Inf(1)
+
+	self.search_rings(x, y, func(index int) {
+		for _, planet := range self.planets[index] {
+			d := Dist(x, y, planet.X, planet.Y) - planet.Radius
+			if d < best_dist || (d == best_dist && planet.Id < ret.Id) {
+				ret, best_dist = planet, d
+			}
+		}
+	}, func() float64 { return best_dist })
+
+	return ret
+}
+
+// search_rings visits cells in square rings of increasing size around (x, y). Anything in a
+// cell of ring k is at least (k - 1) * GRID_CELL away, so after ring k we can stop once the
+// best distance so far is less than k * GRID_CELL.
+
+func (self *Grid) search_rings(x, y float64, visit func(index int), best func() float64) {
+
+	ci, cj := self.cell(x, y)
+
+	for k := 0; k <= self.cols || k <= self.rows; k++ {
+
+		for j := cj - k; j <= cj + k; j++ {
+
+			if j < 0 || j >= self.rows {
+				continue
+			}
+
+			step := 1
+			if j != cj - k && j != cj + k {
+				step = 2 * k						// Only the left and right edges of the ring
+			}
+
+			for i := ci - k; i <= ci + k; i += step {
+				if i >= 0 && i < self.cols {
+					visit(j * self.cols + i)
+				}
+			}
+		}
+
+		if best() < float64(k) * GRID_CELL {
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// grid_test_world is a Grid holding random ships and planets. Ship positions are whole numbers,
+// so that there are plenty of ties for the lowest-ID rule to settle.
+
+func grid_test_world(rng *rand.Rand, width, height, nships, nplanets int) (*Grid, []*Ship, []*Planet) {
+
+	grid := NewGrid(width, height)
+
+	var ships []*Ship
+	var planets []*Planet
+
+	for n := 0; n < nships; n++ {
+		ship := &Ship{
+			Id: n,
+			Owner: rng.Intn(2),
+			X: float64(rng.Intn(width)),
+			Y: float64(rng.Intn(height)),
+		}
+		ships = append(ships, ship)
+		grid.AddShip(ship)
+	}
+
+	for n := 0; n < nplanets; n++ {
+		planet := &Planet{
+			Id: n,
+			X: rng.Float64() * float64(width),
+			Y: rng.Float64() * float64(height),
+			Radius: 3 + rng.Float64() * 10,
+		}
+		planets = append(planets, planet)
+		grid.AddPlanet(planet)
+	}
+
+	return grid, ships, planets
+}
+
+func brute_nearest_ship(ships []*Ship, x, y float64, accept func(*Ship) bool) *Ship {
+
+	var ret *Ship
+	best_dist := math.Inf(1)
+
+	for _, ship := range ships {						// In ID order, so the first of equals wins.
+		if accept != nil && accept(ship) == false {
+			continue
+		}
+		if d := Dist(x, y, ship.X, ship.Y); d < best_dist {
+			ret, best_dist = ship, d
+		}
+	}
+
+	return ret
+}
+
+func brute_nearest_planet(planets []*Planet, x, y float64) *Planet {
+
+	var ret *Planet
+	best_dist := math.Inf(1)
+
+	for _, planet := range planets {
+		if d := Dist(x, y, planet.X, planet.Y) - planet.Radius; d < best_dist {
+			ret, best_dist = planet, d
+		}
+	}
+
+	return ret
+}
+
+func TestGridNearestMatchesScan(t *testing.T) {
+
+	rng := rand.New(rand.NewSource(1))
+
+	enemy := func(ship *Ship) bool { return ship.Owner == 1 }
+
+	for _, nships := range []int{0, 1, 5, 50, 400} {
+
+		grid, ships, planets := grid_test_world(rng, 240, 160, nships, nships / 10 + 1)
+
+		for n := 0; n < 500; n++ {
+
+			// Queries are sometimes off the map (as a course that leaves it might ask about),
+			// sometimes on a ship's exact position.
+
+			x := rng.Float64() * 280 - 20
+			y := rng.Float64() * 200 - 20
+
+			if nships > 0 && n % 5 == 0 {
+				ship := ships[rng.Intn(nships)]
+				x, y = ship.X, ship.Y
+			}
+
+			if got, want := grid.NearestShip(x, y, nil), brute_nearest_ship(ships, x, y, nil); got != want {
+				t.Fatalf("%d ships, NearestShip(%v, %v): got %v, want %v", nships, x, y, got, want)
+			}
+
+			if got, want := grid.NearestShip(x, y, enemy), brute_nearest_ship(ships, x, y, enemy); got != want {
+				t.Fatalf("%d ships, NearestShip(%v, %v, enemy): got %v, want %v", nships, x, y, got, want)
+			}
+
+			if got, want := grid.NearestPlanet(x, y), brute_nearest_planet(planets, x, y); got != want {
+				t.Fatalf("%d ships, NearestPlanet(%v, %v): got %v, want %v", nships, x, y, got, want)
+			}
+
+			r := rng.Float64() * 40
+
+			var want []*Ship
+			for _, ship := range ships {
+				if Dist(x, y, ship.X, ship.Y) <= r {
+					want = append(want, ship)
+				}
+			}
+
+			got := grid.ShipsNear(x, y, r)
+
+			if len(got) != len(want) {
+				t.Fatalf("%d ships, ShipsNear(%v, %v, %v): got %d ships, want %d", nships, x, y, r, len(got), len(want))
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Fatalf("%d ships, ShipsNear(%v, %v, %v): got ship %d at %d, want %d", nships, x, y, r, got[i].Id, i, want[i].Id)
+				}
+			}
+		}
+	}
+}
@@ -47,6 +47,9 @@ func (self *Logfile) Log(format_string string, args ...interface{}) {
 }
 
 func (self *Logfile) LogOnce(format_string string, args ...interface{}) bool {
+	if self == nil {
+		return false
+	}
 	if self.logged_once[format_string] == false {
 		self.logged_once[format_string] = true         // Note that it's format_string that is checked / saved
 		self.Log(format_string, args...)
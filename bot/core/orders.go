@@ -0,0 +1,246 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An Order is what we tell the engine to do with one ship this turn. The zero value is no
+// order at all. Messages (see pilot/messages.go) travel with thrusts: the engine only cares
+// about the angle mod 360, so the message is folded into the angle when the order is sent,
+// which lets replay viewers show it.
+
+type OrderType int
+
+const (
+	NO_ORDER OrderType = iota
+	THRUST
+	DOCK
+	UNDOCK
+)
+
+type Order struct {
+	Type				OrderType
+	Sid					int
+	Speed				int					// THRUST only
+	Angle				int					// THRUST only; 0-359
+	Plid				int					// DOCK only
+	Message				int					// THRUST only; -1 (or anything outside 0-180) for none
+}
+
+func ThrustOrder(sid, speed, degrees int) Order {
+	for degrees < 0 { degrees += 360 }
+	degrees %= 360
+	return Order{Type: THRUST, Sid: sid, Speed: speed, Angle: degrees, Message: -1}
+}
+
+func DockOrder(sid, plid int) Order {
+	return Order{Type: DOCK, Sid: sid, Plid: plid, Message: -1}
+}
+
+func UndockOrder(sid int) Order {
+	return Order{Type: UNDOCK, Sid: sid, Message: -1}
+}
+
+func (self Order) IsNone() bool {
+	return self.Type == NO_ORDER
+}
+
+// Course gives the speed and angle of a thrust. Any other order is 0, 0, i.e. it doesn't move the ship.
+
+func (self Order) Course() (int, int) {
+	if self.Type != THRUST {
+		return 0, 0
+	}
+	return self.Speed, self.Angle
+}
+
+func (self Order) HasMessage() bool {
+	return self.Type == THRUST && self.Message >= 0 && self.Message <= 180
+}
+
+// String is the order without its message, e.g. "t 3 7 90". No order is "".
+
+func (self Order) String() string {
+	switch self.Type {
+	case THRUST:
+		return fmt.Sprintf("t %d %d %d", self.Sid, self.Speed, self.Angle)
+	case DOCK:
+		return fmt.Sprintf("d %d %d", self.Sid, self.Plid)
+	case UNDOCK:
+		return fmt.Sprintf("u %d", self.Sid)
+	}
+	return ""
+}
+
+// RawAngle is the angle with the message (if any) folded in. The engine projects the thrust
+// with this raw angle, not the angle mod 360.
+
+func (self Order) RawAngle() int {
+	if self.Type == THRUST && self.Message >= 0 {
+		return self.Angle + (self.Message + 1) * 360
+	}
+	return self.Angle
+}
+
+// Wire is the order as sent to the engine, with any message folded into the angle.
+
+func (self Order) Wire(no_messages bool) string {
+	if self.HasMessage() && no_messages == false {
+		return fmt.Sprintf("t %d %d %d", self.Sid, self.Speed, self.RawAngle())
+	}
+	return self.String()
+}
+
+// ParseOrders reads a line of orders as sent to the engine (messages included). It's used by
+// the engine to read bots' orders, and by MyBot -rerun to read recorded ones. Malformed input
+// is an error; whether an order makes sense (e.g. is for one of the player's ships) is up to
+// the caller.
+
+func ParseOrders(line string) ([]Order, error) {
+
+	var ret []Order
+
+	tokens := strings.Fields(line)
+
+	for i := 0; i < len(tokens); {
+
+		var needed int
+
+		switch tokens[i] {
+		case "t": needed = 4
+		case "d": needed = 3
+		case "u": needed = 2
+		default:
+			return nil, fmt.Errorf("ParseOrders(): unknown order \"%s\" at token %d", tokens[i], i)
+		}
+
+		if i + needed > len(tokens) {
+			return nil, fmt.Errorf("ParseOrders(): truncated \"%s\" order at token %d", tokens[i], i)
+		}
+
+		var nums []int
+
+		for _, token := range tokens[i + 1 : i + needed] {
+			n, err := strconv.Atoi(token)
+			if err != nil {
+				return nil, fmt.Errorf("ParseOrders(): bad number \"%s\" in \"%s\" order at token %d", token, tokens[i], i)
+			}
+			nums = append(nums, n)
+		}
+
+		switch tokens[i] {
+		case "t":
+			order := ThrustOrder(nums[0], nums[1], nums[2])
+			if nums[2] >= 360 {
+				order.Message = nums[2] / 360 - 1		// So RawAngle() gives back what was sent, even if it's no valid message.
+			}
+			ret = append(ret, order)
+		case "d":
+			ret = append(ret, DockOrder(nums[0], nums[1]))
+		case "u":
+			ret = append(ret, UndockOrder(nums[0]))
+		}
+
+		i += needed
+	}
+
+	return ret, nil
+}
+
+// ---------------------------------------------------------------
+
+func (self *Game) SetOrder(order Order) {
+	if order.IsNone() {
+		delete(self.orders, order.Sid)
+		return
+	}
+	self.orders[order.Sid] = order
+}
+
+// Thrust, Dock and Undock replace the ship's order but keep any message already set on it
+// (see SetMessage), so the two can be called in either order.
+
+func (self *Game) Thrust(ship *Ship, speed, degrees int) {
+	self.set_order_keeping_message(ThrustOrder(ship.Id, speed, degrees))
+}
+
+func (self *Game) Dock(ship *Ship, planet Planet) {
+	self.set_order_keeping_message(DockOrder(ship.Id, planet.Id))
+}
+
+func (self *Game) Undock(ship *Ship) {
+	self.set_order_keeping_message(UndockOrder(ship.Id))
+}
+
+func (self *Game) set_order_keeping_message(order Order) {
+	old, ok := self.orders[order.Sid]
+	if ok {
+		order.Message = old.Message
+	}
+	self.SetOrder(order)
+}
+
+func (self *Game) ClearOrder(ship *Ship) {
+	delete(self.orders, ship.Id)
+}
+
+func (self *Game) ClearOrders() {
+	self.orders = make(map[int]Order)
+}
+
+// SetMessage attaches a message to the ship's current order, which must already exist.
+// Returns false (and does nothing) if there's no order or the message is out of range
+// (i.e. < 0 or > 180). Only thrusts carry messages to the engine (see Wire), but the message
+// stays with the ship if a later Thrust, Dock or Undock replaces the order.
+
+func (self *Game) SetMessage(ship *Ship, message int) bool {
+	if message < 0 || message > 180 {
+		return false
+	}
+	order, ok := self.orders[ship.Id]
+	if ok == false {
+		return false
+	}
+	order.Message = message
+	self.orders[ship.Id] = order
+	return true
+}
+
+// CurrentOrder returns the ship's order, or the zero Order if it has none.
+
+func (self *Game) CurrentOrder(ship *Ship) Order {
+	return self.orders[ship.Id]
+}
+
+// SentOrders is Orders() as Send() would send them: without messages if no_messages is set,
+// and without any message that Wire() would drop anyway (e.g. one set on a dock order).
+
+func (self *Game) SentOrders(no_messages bool) []Order {
+	ret := self.Orders()
+	for i := range ret {
+		if no_messages || ret[i].HasMessage() == false {
+			ret[i].Message = -1
+		}
+	}
+	return ret
+}
+
+// Orders returns every order given this turn, sorted by ship ID.
+
+func (self *Game) Orders() []Order {
+
+	var ret []Order
+
+	for _, order := range self.orders {
+		ret = append(ret, order)
+	}
+
+	sort.Slice(ret, func(a, b int) bool {
+		return ret[a].Sid < ret[b].Sid
+	})
+
+	return ret
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestSetMessage(t *testing.T) {
+
+	game := validate_test_game(t)
+	ship := validate_test_ship(t, game, 0)
+
+	if game.SetMessage(ship, 7) {
+		t.Errorf("SetMessage() succeeded with no order")
+	}
+
+	game.Thrust(ship, 5, 90)
+
+	if game.SetMessage(ship, 181) {
+		t.Errorf("SetMessage() accepted an out of range message")
+	}
+
+	if game.SetMessage(ship, 7) == false {
+		t.Fatalf("SetMessage() failed after Thrust()")
+	}
+
+	game.Thrust(ship, 3, 45)						// A new course shouldn't lose the message.
+
+	order := game.CurrentOrder(ship)
+	if order.Speed != 3 || order.Angle != 45 || order.Message != 7 {
+		t.Errorf("after a second Thrust(), got %v", order)
+	}
+
+	game.SetOrder(ThrustOrder(ship.Id, 3, 45))		// SetOrder() sets exactly what it's given.
+
+	if order := game.CurrentOrder(ship); order.Message != -1 {
+		t.Errorf("after SetOrder(), got message %d", order.Message)
+	}
+}
+
+func TestParseOrdersWireRoundTrip(t *testing.T) {
+
+	with_message := func(order Order, message int) Order {
+		order.Message = message
+		return order
+	}
+
+	orders := []Order{
+		ThrustOrder(0, 7, 90),
+		ThrustOrder(1, 0, 0),
+		ThrustOrder(2, 7, 359),
+		with_message(ThrustOrder(3, 5, 10), 0),
+		with_message(ThrustOrder(4, 5, 10), 180),
+		DockOrder(5, 2),
+		UndockOrder(6),
+	}
+
+	for _, no_messages := range []bool{false, true} {
+
+		var line string
+		for _, order := range orders {
+			line += order.Wire(no_messages) + " "
+		}
+
+		parsed, err := ParseOrders(line)
+		if err != nil {
+			t.Fatalf("ParseOrders(\"%s\"): %v", line, err)
+		}
+
+		if len(parsed) != len(orders) {
+			t.Fatalf("ParseOrders(\"%s\"): got %d orders, want %d", line, len(parsed), len(orders))
+		}
+
+		for i, order := range orders {
+			if no_messages {
+				order.Message = -1
+			}
+			if parsed[i] != order {
+				t.Errorf("no_messages %v: sent %+v as \"%s\", got back %+v", no_messages, order, order.Wire(no_messages), parsed[i])
+			}
+			if parsed[i].Wire(false) != order.Wire(no_messages) {
+				t.Errorf("no_messages %v: \"%s\" went out again as \"%s\"", no_messages, order.Wire(no_messages), parsed[i].Wire(false))
+			}
+		}
+	}
+}
+
+func TestParseOrdersRejectsBadLines(t *testing.T) {
+
+	for _, line := range []string{
+		"x 1 2",
+		"t 1 2",
+		"d 1",
+		"u",
+		"t 1 two 90",
+		"t 1 2 90 d",
+	} {
+		if _, err := ParseOrders(line); err == nil {
+			t.Errorf("ParseOrders(\"%s\") gave no error", line)
+		}
+	}
+
+	if orders, err := ParseOrders(""); err != nil || len(orders) != 0 {
+		t.Errorf("ParseOrders(\"\"): got %v, %v; want no orders", orders, err)
+	}
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Params holds the tuned numbers that used to be constants scattered around the bot. The game
+// carries one set (the defaults unless told otherwise) so any package can reach them. A set can
+// be loaded from a JSON file, e.g.
+//
+//     {"threat_range": 22, "ga_iterations": 20000}
+//
+// Fields not in the file keep their defaults.
+
+type Params struct {
+	ThreatRange				float64			`json:"threat_range"`		// Enemies this close to a planet threaten it (see UpdateEnemyMaps)
+	PanicRange				float64			`json:"panic_range"`		// How far the enemy can get in the rush GA before we worry
+	DodgeMargin				float64			`json:"dodge_margin"`		// Clearance for navigation waypoints around obstacles
+	GAIterations			int				`json:"ga_iterations"`		// Rush GA iterations per turn (if the budget allows)
+	SafeMoveRange			float64			`json:"safe_move_range"`	// ExecuteSafely() only checks pairs of ships this close
+}
+
+func DefaultParams() Params {
+	return Params{
+		ThreatRange: 20,					// This value seems to be surprisingly fine-tuned.
+		PanicRange: 30,
+		DodgeMargin: 1.5,
+		GAIterations: 15000,
+		SafeMoveRange: 15,
+	}
+}
+
+func LoadParams(filename string) (Params, error) {
+
+	ret := DefaultParams()
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return ret, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()						// Catch typos, which would otherwise silently do nothing.
+
+	err = decoder.Decode(&ret)
+	if err != nil {
+		return DefaultParams(), fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return ret, nil
+}
+
+func (self *Game) Params() *Params {
+	return &self.params
+}
+
+func (self *Game) SetParams(params Params) {
+	self.params = params
+}
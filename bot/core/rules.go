@@ -0,0 +1,182 @@
+package core
+
+import (
+	"math"
+)
+
+// The rest of the game's rules: what happens during a turn, beyond what a bot needs to know to
+// play. The engine (which runs games) and genetic.FullSim (which looks ahead) both use these,
+// so that they can't drift apart.
+
+const (
+	MAX_SHIP_HEALTH = 255
+	DOCK_TURNS = 5
+	PRODUCTION_PER_SHIP = 72
+	BASE_PRODUCTIVITY = 6
+	ADDITIONAL_PRODUCTIVITY = 6
+	SPAWN_RADIUS = 2.0
+	SPAWN_FREE_DIST = 2.0
+	EXPLOSION_RADIUS = 10.0
+	ATTACK_RANGE = WEAPON_RANGE + SHIP_RADIUS * 2		// Centre to centre
+)
+
+// Production is what a planet produces in a turn, given how many ships are fully docked at it.
+
+func Production(docked int) int {
+	if docked <= 0 {
+		return 0
+	}
+	return BASE_PRODUCTIVITY + (docked - 1) * ADDITIONAL_PRODUCTIVITY
+}
+
+func InDockingRange(ship_x, ship_y, planet_x, planet_y, planet_radius float64) bool {
+	return Dist(ship_x, ship_y, planet_x, planet_y) - planet_radius <= DOCKING_RADIUS + SHIP_RADIUS
+}
+
+// ---------------------------------------------------------------
+
+type DockRequest struct {
+	Pid					int
+	Sid					int
+	Refusal				string				// Set by ResolveDocks(); "" if the dock went ahead
+}
+
+// ResolveDocks settles one planet's docks for the turn, given its owner (-1 for none) and how many
+// spots are free. The requests should be in player order, then order order; each one is either
+// refused (with the reason) or goes ahead while there's room. If several players try to dock at a
+// neutral planet on the same turn it's contested and nobody docks. Returns the planet's new owner.
+
+func ResolveDocks(owner, free_spots int, requests []*DockRequest) int {
+
+	contested := false
+
+	for _, req := range requests {
+		if req.Pid != requests[0].Pid {
+			contested = true
+		}
+	}
+
+	for _, req := range requests {
+
+		if owner == -1 && contested {
+			req.Refusal = "planet is contested"
+		} else if owner != -1 && owner != req.Pid {
+			req.Refusal = "planet is owned by an enemy"
+		} else if free_spots <= 0 {
+			req.Refusal = "planet is full"
+		} else {
+			owner = req.Pid
+			free_spots--
+		}
+	}
+
+	return owner
+}
+
+// ---------------------------------------------------------------
+
+// SpawnLocation finds where a planet's new ship goes, as the official engine does: the candidates
+// are an integer grid of offsets from the planet's surface, and the free one closest to the centre
+// of the map wins. occupied() says whether some ship is too close (see SPAWN_FREE_DIST) to a point.
+// This matches ~90% of the spawns in our reference replays; the engine's exact notion of "free"
+// is unknown.
+
+func SpawnLocation(planet_x, planet_y, planet_radius float64, width, height int, occupied func(x, y float64) bool) (float64, float64, bool) {
+
+	best_x, best_y, best_dist, found := 0.0, 0.0, 0.0, false
+
+	for dx := -SPAWN_RADIUS; dx <= SPAWN_RADIUS; dx++ {
+		for dy := -SPAWN_RADIUS; dy <= SPAWN_RADIUS; dy++ {
+
+			if dx == 0 && dy == 0 {
+				continue							// That's a point on the planet's surface, so a new ship would hit it.
+			}
+
+			angle := math.Atan2(dy, dx)
+			x := planet_x + dx + planet_radius * math.Cos(angle)
+			y := planet_y + dy + planet_radius * math.Sin(angle)
+
+			dist := Dist(x, y, float64(width) / 2, float64(height) / 2)
+
+			if found && dist >= best_dist {
+				continue
+			}
+
+			if x <= 0 || y <= 0 || x >= float64(width) || y >= float64(height) {
+				continue
+			}
+
+			if occupied(x, y) == false {
+				best_x, best_y, best_dist, found = x, y, dist, true
+			}
+		}
+	}
+
+	return best_x, best_y, found
+}
+
+// ExplosionDamage is the damage an exploding planet does to a ship whose centre is dist from the
+// planet's surface. It falls off linearly from the surface to EXPLOSION_RADIUS beyond it.
+
+func ExplosionDamage(dist float64) int {
+	if dist >= EXPLOSION_RADIUS {
+		return 0
+	}
+	return int(math.Round(MAX_SHIP_HEALTH * (1 - MaxFloat(dist, 0) / EXPLOSION_RADIUS)))
+}
+
+// CollisionTime is when, during the turn, two moving things first come within r of each other,
+// given the differences in their positions (dx, dy) and velocities (dvx, dvy).
+
+func CollisionTime(r, dx, dy, dvx, dvy float64) (float64, bool) {
+
+	// https://github.com/HaliteChallenge/Halite-II/blob/master/environment/core/SimulationEvent.cpp#L100
+	//
+	// With credit to Ben Spector
+	// Simplified derivation:
+	// 1. Set up the distance between the two entities in terms of time,
+	//    the difference between their velocities and the difference between
+	//    their positions
+	// 2. Equate the distance equal to the event radius (max possible distance
+	//    they could be)
+	// 3. Solve the resulting quadratic
+
+	// Quadratic formula
+	a := dvx * dvx + dvy * dvy				// const auto a = std::pow(dvx, 2) + std::pow(dvy, 2);
+	b := 2 * (dx * dvx + dy * dvy)			// const auto b = 2 * (dx * dvx + dy * dvy);
+	c := dx * dx + dy * dy - r * r			// const auto c = std::pow(dx, 2) + std::pow(dy, 2) - std::pow(r, 2);
+
+	disc := b * b - 4 * a * c				// disc := std::pow(b, 2) - 4 * a * c;
+
+	if (a == 0.0) {
+		if (b == 0.0) {
+			if (c <= 0.0) {
+				// Implies r^2 >= dx^2 + dy^2 and the two are already colliding
+				return 0.0, true
+			}
+			return 0.0, false
+		}
+		t := -c / b
+		if (t >= 0.0) {
+			return t, true
+		}
+		return 0.0, false
+	} else if (disc == 0.0) {
+		// One solution
+		t := -b / (2 * a)
+		return t, true
+	} else if (disc > 0) {
+		t1 := -b + math.Sqrt(disc)
+		t2 := -b - math.Sqrt(disc)
+
+		if (t1 >= 0.0 && t2 >= 0.0) {
+			return MinFloat(t1, t2) / (2 * a), true
+		} else if (t1 <= 0.0 && t2 <= 0.0) {
+			return MaxFloat(t1, t2) / (2 * a), true
+		} else {
+			return 0.0, true
+		}
+	} else {
+		return 0.0, false
+	}
+}
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A session file records everything a game said to us and everything we said back, so a
+// ladder game can be re-run offline (MyBot -rerun). It's plain text, one record per line:
+//
+//     args <our command line flags>
+//     init <pid> <width> <height> <the init frame's tokens>
+//     frame <turn> <the frame's tokens, as parsed>
+//     sent <turn> <the orders line, as sent>
+//
+// Like the Logfile, a nil *Sessionfile is fine to use and does nothing.
+
+type Sessionfile struct {
+	outfile			*os.File
+	writer			*bufio.Writer
+}
+
+func NewSession(outfilename string) (*Sessionfile, error) {
+	outfile, err := os.Create(outfilename)
+	if err != nil {
+		return nil, err
+	}
+	return &Sessionfile{outfile, bufio.NewWriter(outfile)}, nil
+}
+
+func (self *Sessionfile) Record(kind string, format_string string, args ...interface{}) {
+	if self == nil {
+		return
+	}
+	fmt.Fprintf(self.writer, kind + " " + format_string + "\n", args...)
+	self.writer.Flush()										// We may be killed at any moment.
+}
+
+// ---------------------------------------------------------------
+
+// StartSession begins recording. Call it after the init stage; the init record is
+// written at once, since the init frame has already been parsed.
+
+func (self *Game) StartSession(outfilename string, args []string) error {
+
+	session, err := NewSession(outfilename)
+	if err != nil {
+		return err
+	}
+
+	self.session = session
+	self.session.Record("args", "%s", strings.Join(args, " "))
+	self.session.Record("init", "%d %d %d %s", self.pid, self.width, self.height, self.raw)
+
+	return nil
+}
+
+// ---------------------------------------------------------------
+
+type Session struct {
+	Args			string
+	Pid				int
+	Width			int
+	Height			int
+	InitFrame		string
+	Frames			[]string			// Indexed by turn: the frame's tokens
+	Sent			[]string			// Indexed by turn: what we sent ("" if nothing was recorded)
+}
+
+func LoadSession(filename string) (*Session, error) {
+
+	infile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+
+	ret := new(Session)
+	inited := false
+
+	scanner := bufio.NewScanner(infile)
+	scanner.Buffer(make([]byte, 64 * 1024), 64 * 1024 * 1024)
+
+	for line := 1; scanner.Scan(); line++ {
+
+		fields := strings.SplitN(scanner.Text(), " ", 3)
+
+		bad := func() error {
+			return fmt.Errorf("LoadSession(): %s: bad line %d", filename, line)
+		}
+
+		switch fields[0] {
+
+		case "args":
+
+			ret.Args = strings.TrimPrefix(scanner.Text(), "args ")
+
+		case "init":
+
+			init_fields := strings.SplitN(scanner.Text(), " ", 5)
+			if len(init_fields) != 5 {
+				return nil, bad()
+			}
+
+			_, err := fmt.Sscanf(strings.Join(init_fields[1:4], " "), "%d %d %d", &ret.Pid, &ret.Width, &ret.Height)
+			if err != nil {
+				return nil, bad()
+			}
+
+			ret.InitFrame = init_fields[4]
+
+			inited = true
+
+		case "frame", "sent":
+
+			if len(fields) < 2 {
+				return nil, bad()
+			}
+
+			turn, err := strconv.Atoi(fields[1])
+			if err != nil || turn < 0 {
+				return nil, bad()
+			}
+
+			content := ""
+			if len(fields) == 3 {
+				content = fields[2]
+			}
+
+			if fields[0] == "frame" {
+				if turn != len(ret.Frames) {
+					return nil, fmt.Errorf("LoadSession(): %s: line %d: frame %d out of order", filename, line, turn)
+				}
+				ret.Frames = append(ret.Frames, content)
+			} else {
+				for len(ret.Sent) <= turn {
+					ret.Sent = append(ret.Sent, "")
+				}
+				ret.Sent[turn] = content
+			}
+
+		default:
+
+			return nil, bad()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if inited == false || len(ret.Frames) == 0 {
+		return nil, fmt.Errorf("LoadSession(): %s: no init or frames", filename)
+	}
+
+	return ret, nil
+}
+
+// Reader gives the recorded input in the form NewGameFromIO() expects.
+
+func (self *Session) Reader() io.Reader {
+	return strings.NewReader(fmt.Sprintf("%d\n%d %d\n%s\n%s\n", self.Pid, self.Width, self.Height, self.InitFrame, strings.Join(self.Frames, "\n")))
+}
+
+// SameOrders compares two turns' orders, ignoring the order they're in (the bot sends them
+// in map order). Messages count.
+
+func SameOrders(a, b []Order) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	a = sorted_orders(a)
+	b = sorted_orders(b)
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sorted_orders(orders []Order) []Order {
+	ret := append([]Order(nil), orders...)
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Sid < ret[j].Sid
+	})
+	return ret
+}
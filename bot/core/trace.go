@@ -0,0 +1,92 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// A Tracefile is the structured counterpart of a Logfile: each record is written as one line
+// of JSON, for offline tools to filter (see cmd/trace). Like Logfile, a nil *Tracefile is
+// fine to use and does nothing, and the file is only created when something is written.
+// Unlike Logfile, if the file can't be created (or written) it gives up for good.
+
+type Tracefile struct {
+	outfile			*os.File
+	outfilename		string
+	encoder			*json.Encoder
+	failed			bool
+}
+
+func NewTrace(outfilename string) *Tracefile {
+	return &Tracefile{
+		outfilename: outfilename,
+	}
+}
+
+// Write adds a record. The first failure is returned; after that, nothing more is written
+// and the error is nil.
+
+func (self *Tracefile) Write(record interface{}) error {
+
+	if self == nil || self.failed {
+		return nil
+	}
+
+	if self.outfile == nil {
+
+		var err error
+
+		self.outfile, err = os.Create(self.outfilename)
+		if err != nil {
+			self.failed = true
+			return err
+		}
+
+		self.encoder = json.NewEncoder(self.outfile)
+	}
+
+	err := self.encoder.Encode(record)			// Adds the newline. Not buffered, so nothing needs flushing.
+	if err != nil {
+		self.Close()
+		self.failed = true
+		return err
+	}
+
+	return nil
+}
+
+func (self *Tracefile) Close() {
+	if self == nil || self.outfile == nil {
+		return
+	}
+	self.outfile.Close()
+	self.outfile = nil
+	self.failed = true							// So a later Write() doesn't start the file again.
+}
+
+// ---------------------------------------------------------------
+
+func (self *Game) StartTrace(tracefilename string) {
+	self.tracefile = NewTrace(tracefilename)
+}
+
+func (self *Game) Tracing() bool {
+	return self.tracefile != nil
+}
+
+// Trace writes a record. If that fails, the error is logged and tracing stops.
+
+func (self *Game) Trace(record interface{}) {
+	err := self.tracefile.Write(record)
+	if err != nil {
+		self.Log("Tracing stopped: %v", err)
+		self.tracefile = nil
+	}
+}
+
+// StopTrace closes the trace file, e.g. at the end of the game.
+
+func (self *Game) StopTrace() {
+	self.tracefile.Close()
+	self.tracefile = nil
+}
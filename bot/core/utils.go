@@ -4,8 +4,6 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"math"
-	"strconv"
-	"strings"
 )
 
 func ShipsWillCollide(ship_a *Ship, speed_a, angle_a, msg1 int, ship_b *Ship, speed_b, angle_b, msg2 int) bool {
@@ -156,42 +154,6 @@ func Dist(x1, y1, x2, y2 float64) float64 {
 	return math.Sqrt(dx * dx + dy * dy)
 }
 
-func CourseFromString(s string) (int, int) {
-
-	tokens := strings.Fields(s)
-
-	if len(tokens) != 4 {			// t sid speed angle
-		return 0, 0
-	}
-
-	if tokens[0] != "t" {
-		return 0, 0
-	}
-
-	speed, err1 := strconv.Atoi(tokens[2])
-	degrees, err2 := strconv.Atoi(tokens[3])
-
-	if err1 != nil || err2 != nil {
-		return 0, 0
-	}
-
-	for degrees < 0 {				// FIXME: this is dumb, what's the mathematical way?
-		degrees += 360
-	}
-
-	degrees %= 360
-
-	return speed, degrees
-}
-
-func GetOrderType(s string) string {
-	if len(s) == 0 { return ""  }
-	if s[0] == 't' { return "t" }
-	if s[0] == 'd' { return "d" }
-	if s[0] == 'u' { return "u" }
-	return "?"
-}
-
 func HashFromString(datastring string) string {
 	data := []byte(datastring)
 	sum := sha1.Sum(data)
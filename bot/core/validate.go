@@ -0,0 +1,196 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The validator checks the turn's orders against the parsed state, just before they're sent.
+// The AI is meant to get all this right itself, so any problem found is a bug somewhere; but
+// the engine's response to most bad orders is to kill the ship, so it's better to catch them.
+//
+// In repair mode, bad orders are fixed (a speed is clamped, anything else becomes a null move)
+// and logged. In strict mode nothing is changed and the problems come back as an error, which
+// is what the golden tool's -strict flag uses.
+
+type OrderProblem struct {
+	Order				Order
+	Reason				string
+}
+
+func (self OrderProblem) String() string {
+	return fmt.Sprintf("\"%s\": %s", self.Order.String(), self.Reason)
+}
+
+type OrderError struct {
+	Turn				int
+	Problems			[]OrderProblem
+}
+
+func (self *OrderError) Error() string {
+	var reasons []string
+	for _, problem := range self.Problems {
+		reasons = append(reasons, problem.String())
+	}
+	return fmt.Sprintf("turn %d: %d bad orders: %s", self.Turn, len(self.Problems), strings.Join(reasons, "; "))
+}
+
+// ValidateOrders checks every order. In strict mode it returns an *OrderError if anything is
+// wrong, and changes nothing. Otherwise it repairs what it can, logs it, and returns nil.
+
+func (self *Game) ValidateOrders(strict bool) error {
+
+	var problems []OrderProblem
+
+	report := func(order Order, format_string string, args ...interface{}) {
+		problems = append(problems, OrderProblem{order, fmt.Sprintf(format_string, args...)})
+	}
+
+	for _, order := range self.Orders() {
+
+		ship, ok := self.GetShip(order.Sid)
+
+		if ok == false || ship.Owner != self.pid {
+			report(order, "not one of our ships")
+			if strict == false { delete(self.orders, order.Sid) }
+			continue
+		}
+
+		switch order.Type {
+
+		case THRUST:
+
+			if ship.CanMove() == false {
+				report(order, "ship is not undocked")
+				if strict == false { self.ClearOrder(ship) }
+				continue
+			}
+
+			if order.Speed < 0 || order.Speed > MAX_SPEED {
+				report(order, "speed out of range 0-%d", MAX_SPEED)
+				if strict == false {
+					order.Speed = Max(0, Min(MAX_SPEED, order.Speed))
+					self.SetOrder(order)
+				}
+			}
+
+			if self.CourseStaysInBounds(ship, order.Speed, order.Angle) == false {
+				report(order, "leaves the map")
+				if strict == false { self.ClearOrder(ship) }
+			}
+
+		case DOCK:
+
+			planet, ok := self.GetPlanet(order.Plid)
+
+			if ok == false {
+				report(order, "no such planet")
+				if strict == false { self.ClearOrder(ship) }
+				continue
+			}
+
+			if ship.CanMove() == false {
+				report(order, "ship is not undocked")
+				if strict == false { self.ClearOrder(ship) }
+				continue
+			}
+
+			if ship.CanDock(planet) == false {
+				report(order, "can't dock at %s (approach distance %.2f)", planet.String(), ship.ApproachDist(planet))
+				if strict == false { self.ClearOrder(ship) }
+			}
+
+		case UNDOCK:
+
+			if ship.DockedStatus != DOCKED {
+				report(order, "ship is not fully docked")
+				if strict == false { self.ClearOrder(ship) }
+			}
+		}
+	}
+
+	// Collisions between our own ships (ignoring those that die at time 0 anyway, as the
+	// pilots' own collision checks do). When repairing, the moving ship of a colliding pair is
+	// stopped; if both are moving, the one with the higher ID. Stopping a ship can put it in
+	// someone else's way, so keep going until nothing changes.
+
+	for {
+
+		a, b, ok := self.first_own_collision()
+		if ok == false {
+			break
+		}
+
+		report(self.orders[b.Id], "collides with ship %d", a.Id)
+
+		if strict {
+			break
+		}
+
+		self.ClearOrder(b)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if strict {
+		return &OrderError{self.turn, problems}
+	}
+
+	for _, problem := range problems {
+		self.Log("ValidateOrders(): repaired %s", problem.String())
+	}
+
+	return nil
+}
+
+// first_own_collision returns a pair of our ships whose orders make them collide. The second
+// ship is always one that is moving.
+
+func (self *Game) first_own_collision() (*Ship, *Ship, bool) {
+
+	my_ships := self.MyShips()
+
+	for i, ship_a := range my_ships {
+
+		if ship_a.Doomed {
+			continue
+		}
+
+		speed_a, angle_a := self.orders[ship_a.Id].Course()
+		msg_a := self.order_message(ship_a)
+
+		for _, ship_b := range my_ships[i + 1:] {
+
+			speed_b, angle_b := self.orders[ship_b.Id].Course()
+
+			if ship_b.Doomed || (speed_a == 0 && speed_b == 0) {
+				continue
+			}
+
+			if ship_a.Dist(ship_b) > float64(speed_a + speed_b) + SHIP_RADIUS * 2 {
+				continue
+			}
+
+			msg_b := self.order_message(ship_b)
+
+			if ShipsWillCollide(ship_a, speed_a, angle_a, msg_a, ship_b, speed_b, angle_b, msg_b) {
+				if speed_b > 0 {
+					return ship_a, ship_b, true
+				}
+				return ship_b, ship_a, true
+			}
+		}
+	}
+
+	return nil, nil, false
+}
+
+func (self *Game) order_message(ship *Ship) int {
+	order := self.orders[ship.Id]
+	if order.HasMessage() {
+		return order.Message
+	}
+	return -1
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// A frame, in the format the engine sends, with (for player 0):
+//
+//     ship 0    undocked at (50, 50)
+//     ship 1    docked at planet 0
+//     ship 2    undocked at (150, 50), far from planet 1
+//     ship 3    undocked at (54, 50), just ahead of ship 0
+//
+// Player 1 has one ship, far away.
+
+const VALIDATE_TEST_FRAME = "2 " +
+	"0 4 " +
+	"0 50.0 50.0 255 0 0 0 0 0 0 " +
+	"1 100.0 100.0 255 0 0 2 0 0 0 " +
+	"2 150.0 50.0 255 0 0 0 0 0 0 " +
+	"3 54.0 50.0 255 0 0 0 0 0 0 " +
+	"1 1 " +
+	"4 200.0 140.0 255 0 0 0 0 0 0 " +
+	"2 " +
+	"0 100.0 108.0 2000 5.0 3 0 1000 1 0 1 1 " +
+	"1 150.0 80.0 2000 5.0 3 0 1000 0 0 0"
+
+func validate_test_game(t *testing.T) *Game {
+
+	input := "0\n240 160\n" + VALIDATE_TEST_FRAME + "\n" + VALIDATE_TEST_FRAME + "\n"
+
+	game, err := ReadGame(strings.NewReader(input), ioutil.Discard)
+	if err != nil {
+		t.Fatalf("ReadGame(): %v", err)
+	}
+
+	err = game.Parse()
+	if err != nil {
+		t.Fatalf("Parse(): %v", err)
+	}
+
+	return game
+}
+
+func validate_test_ship(t *testing.T, game *Game, sid int) *Ship {
+	ship, ok := game.GetShip(sid)
+	if ok == false {
+		t.Fatalf("no ship %d", sid)
+	}
+	return ship
+}
+
+func TestValidateOrders(t *testing.T) {
+
+	tests := []struct {
+		name			string
+		give			func(game *Game)
+		sid				int					// The ship whose order is bad
+		reason			string				// Start of the reason strict mode should give
+		repaired		Order				// The ship's order after repair
+	}{
+		{
+			name: "docked ship thrusting",
+			give: func(game *Game) { game.Thrust(validate_test_ship(t, game, 1), 3, 270) },
+			sid: 1,
+			reason: "ship is not undocked",
+			repaired: Order{},
+		},
+		{
+			name: "speed over MAX_SPEED",
+			give: func(game *Game) { game.Thrust(validate_test_ship(t, game, 2), MAX_SPEED + 2, 90) },
+			sid: 2,
+			reason: "speed out of range",
+			repaired: ThrustOrder(2, MAX_SPEED, 90),
+		},
+		{
+			name: "dock outside DOCKING_RADIUS",
+			give: func(game *Game) {
+				planet, _ := game.GetPlanet(1)
+				game.Dock(validate_test_ship(t, game, 2), *planet)
+			},
+			sid: 2,
+			reason: "can't dock at",
+			repaired: Order{},
+		},
+		{
+			name: "two of our ships colliding",
+			give: func(game *Game) { game.Thrust(validate_test_ship(t, game, 0), 7, 0) },
+			sid: 0,
+			reason: "collides with ship 3",
+			repaired: Order{},
+		},
+	}
+
+	for _, test := range tests {
+
+		// Strict mode: the problem is reported and nothing changes.
+
+		game := validate_test_game(t)
+		test.give(game)
+		before := game.Orders()
+
+		err := game.ValidateOrders(true)
+
+		order_err, ok := err.(*OrderError)
+		if ok == false {
+			t.Errorf("%s: strict: got %v, want an *OrderError", test.name, err)
+		} else if len(order_err.Problems) != 1 {
+			t.Errorf("%s: strict: got %d problems, want 1: %v", test.name, len(order_err.Problems), err)
+		} else if order_err.Problems[0].Order.Sid != test.sid || strings.HasPrefix(order_err.Problems[0].Reason, test.reason) == false {
+			t.Errorf("%s: strict: got %v, want ship %d: \"%s...\"", test.name, err, test.sid, test.reason)
+		}
+
+		if SameOrders(game.Orders(), before) == false {
+			t.Errorf("%s: strict: orders changed from %v to %v", test.name, before, game.Orders())
+		}
+
+		// Repair mode: no error, and the order is fixed.
+
+		game = validate_test_game(t)
+		test.give(game)
+
+		err = game.ValidateOrders(false)
+		if err != nil {
+			t.Errorf("%s: repair: got %v, want nil", test.name, err)
+		}
+
+		got := game.CurrentOrder(validate_test_ship(t, game, test.sid))
+		if got != test.repaired {
+			t.Errorf("%s: repair: order is \"%s\", want \"%s\"", test.name, got.String(), test.repaired.String())
+		}
+
+		if err = game.ValidateOrders(true); err != nil {
+			t.Errorf("%s: repair: orders still bad: %v", test.name, err)
+		}
+	}
+}
+
+func TestValidateOrdersAcceptsGoodOrders(t *testing.T) {
+
+	game := validate_test_game(t)
+
+	game.Thrust(validate_test_ship(t, game, 0), 7, 180)
+	game.Undock(validate_test_ship(t, game, 1))
+	game.Thrust(validate_test_ship(t, game, 2), 7, 90)		// Towards planet 1, but not into it
+
+	before := game.Orders()
+
+	if err := game.ValidateOrders(true); err != nil {
+		t.Errorf("strict: got %v, want nil", err)
+	}
+
+	if err := game.ValidateOrders(false); err != nil || SameOrders(game.Orders(), before) == false {
+		t.Errorf("repair: got %v and orders %v, want nil and %v", err, game.Orders(), before)
+	}
+}
@@ -0,0 +1,11 @@
+package engine
+
+// The rules live in the core package (constants.go and rules.go), which the bots and the
+// genetic simulator share. These extra values are only needed to run games.
+
+const (
+	MAX_TURNS = 300
+	SHIPS_PER_PLAYER = 3
+	HEALTH_PER_RADIUS = 255
+	RESOURCES_PER_RADIUS = 144
+)
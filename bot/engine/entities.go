@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"fmt"
+
+	hal "../core"
+)
+
+type Ship struct {
+	Id					int
+	Owner				int
+	X					float64
+	Y					float64
+	HP					int
+	DockedStatus		hal.DockedStatus
+	DockedPlanet		int				// -1 when undocked
+	DockingProgress		int				// Turns left in the DOCKING or UNDOCKING state
+
+	vel_x				float64			// Only valid during turn resolution
+	vel_y				float64
+	fired				bool
+}
+
+func (self *Ship) Alive() bool {
+	return self.HP > 0
+}
+
+func (self *Ship) String() string {
+	return fmt.Sprintf("Ship %d [%d,%d]", self.Id, int(self.X), int(self.Y))
+}
+
+type Planet struct {
+	Id					int
+	X					float64
+	Y					float64
+	HP					int
+	Radius				float64
+	DockingSpots		int
+	CurrentProduction	int
+	RemainingProduction	int
+	Owner				int				// -1 when not owned
+	Docked				[]int			// Ship IDs, in docking order
+}
+
+func (self *Planet) Alive() bool {
+	return self.HP > 0
+}
+
+func (self *Planet) IsFull() bool {
+	return len(self.Docked) >= self.DockingSpots
+}
+
+func (self *Planet) String() string {
+	return fmt.Sprintf("Planet %d [%d,%d]", self.Id, int(self.X), int(self.Y))
+}
+
+func (self *Planet) remove_docked(sid int) {
+	for i, docked_sid := range self.Docked {
+		if docked_sid == sid {
+			self.Docked = append(self.Docked[:i], self.Docked[i+1:]...)
+			break
+		}
+	}
+	if len(self.Docked) == 0 {
+		self.Owner = -1
+		self.CurrentProduction = 0
+	}
+}
+
+type PlayerStats struct {
+	Name				string
+	Alive				bool
+	LastFrameAlive		int
+	ShipsProduced		int				// Not counting the initial ships
+	DamageDealt			int
+	Error				string			// Why we kicked the player out, if we did
+}
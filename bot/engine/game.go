@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	hal "../core"
+	rep "../replay"
+)
+
+type Game struct {
+	turn				int
+	width				int
+	height				int
+	seed				int64
+	rng					*rand.Rand
+
+	ships				map[int]*Ship		// Ship ID --> Ship (living ships only)
+	planets				map[int]*Planet		// Planet ID --> Planet (living planets only)
+	next_sid			int
+
+	stats				[]*PlayerStats		// Indexed by player ID
+	events				[]*rep.Event		// What happened in the last Step(), for replays
+}
+
+// NewGame generates a fresh map. A width or height of 0 means choose one from the seed, as the official engine would.
+
+func NewGame(players int, seed int64, width, height int) (*Game, error) {
+
+	if players != 2 && players != 4 {
+		return nil, fmt.Errorf("NewGame(): can't make a map for %d players", players)
+	}
+
+	game := new(Game)
+	game.seed = seed
+	game.rng = rand.New(rand.NewSource(seed))
+
+	if width <= 0 || height <= 0 {
+		width = 240 + 24 * game.rng.Intn(7)
+		height = width * 2 / 3
+	}
+
+	game.width = width
+	game.height = height
+	game.ships = make(map[int]*Ship)
+	game.planets = make(map[int]*Planet)
+
+	for pid := 0; pid < players; pid++ {
+		game.stats = append(game.stats, &PlayerStats{Alive: true})
+	}
+
+	game.generate_ships()
+	game.generate_planets()
+
+	return game, nil
+}
+
+func (self *Game) Turn() int { return self.turn }
+func (self *Game) Width() int { return self.width }
+func (self *Game) Height() int { return self.height }
+func (self *Game) Seed() int64 { return self.seed }
+func (self *Game) Players() int { return len(self.stats) }
+
+func (self *Game) Stats(pid int) PlayerStats {
+	return *self.stats[pid]
+}
+
+func (self *Game) SetName(pid int, name string) {
+	self.stats[pid].Name = name
+}
+
+func (self *Game) GetShip(sid int) (*Ship, bool) {
+	ret, ok := self.ships[sid]
+	return ret, ok
+}
+
+func (self *Game) GetPlanet(plid int) (*Planet, bool) {
+	ret, ok := self.planets[plid]
+	return ret, ok
+}
+
+func (self *Game) AllShips() []*Ship {
+	var ret []*Ship
+	for _, ship := range self.ships {
+		ret = append(ret, ship)
+	}
+	sort.Slice(ret, func(a, b int) bool {
+		return ret[a].Id < ret[b].Id
+	})
+	return ret
+}
+
+func (self *Game) AllPlanets() []*Planet {
+	var ret []*Planet
+	for _, planet := range self.planets {
+		ret = append(ret, planet)
+	}
+	sort.Slice(ret, func(a, b int) bool {
+		return ret[a].Id < ret[b].Id
+	})
+	return ret
+}
+
+func (self *Game) ShipsOwnedBy(pid int) []*Ship {
+	var ret []*Ship
+	for _, ship := range self.AllShips() {
+		if ship.Owner == pid {
+			ret = append(ret, ship)
+		}
+	}
+	return ret
+}
+
+func (self *Game) AlivePlayers() []int {
+	var ret []int
+	for pid, stats := range self.stats {
+		if stats.Alive {
+			ret = append(ret, pid)
+		}
+	}
+	return ret
+}
+
+// Kick is used when a player's bot misbehaves (crash, timeout). Its ships are removed from the game.
+
+func (self *Game) Kick(pid int, reason string) {
+
+	self.stats[pid].Alive = false
+	self.stats[pid].Error = reason
+
+	for _, ship := range self.ShipsOwnedBy(pid) {
+		self.destroy_ship(ship)
+	}
+}
+
+// Over returns true when the turn limit is reached or at most one player remains.
+
+func (self *Game) Over() bool {
+	if self.turn >= MAX_TURNS {
+		return true
+	}
+	return len(self.AlivePlayers()) <= 1
+}
+
+// Ranks returns each player's final rank (1 is the winner). Survival time is what matters most;
+// among players alive at the same time we prefer more ships, then more total health, then more damage dealt.
+
+func (self *Game) Ranks() []int {
+
+	type entry struct {
+		pid			int
+		alive		int
+		ships		int
+		health		int
+		damage		int
+	}
+
+	var entries []*entry
+
+	for pid, stats := range self.stats {
+		e := &entry{pid: pid, alive: stats.LastFrameAlive, damage: stats.DamageDealt}
+		for _, ship := range self.ShipsOwnedBy(pid) {
+			e.ships++
+			e.health += ship.HP
+		}
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool {
+		if entries[a].alive != entries[b].alive { return entries[a].alive > entries[b].alive }
+		if entries[a].ships != entries[b].ships { return entries[a].ships > entries[b].ships }
+		if entries[a].health != entries[b].health { return entries[a].health > entries[b].health }
+		return entries[a].damage > entries[b].damage
+	})
+
+	ret := make([]int, len(entries))
+	for i, e := range entries {
+		ret[e.pid] = i + 1
+	}
+	return ret
+}
+
+func (self *Game) add_ship(pid int, x, y float64) *Ship {
+	ship := &Ship{
+		Id: self.next_sid,
+		Owner: pid,
+		X: x,
+		Y: y,
+		HP: hal.MAX_SHIP_HEALTH,
+		DockedPlanet: -1,
+	}
+	self.ships[ship.Id] = ship
+	self.next_sid++
+	return ship
+}
+
+func (self *Game) destroy_ship(ship *Ship) {
+
+	ship.HP = 0
+
+	if ship.DockedPlanet != -1 {
+		planet, ok := self.planets[ship.DockedPlanet]
+		if ok {
+			planet.remove_docked(ship.Id)
+		}
+	}
+
+	delete(self.ships, ship.Id)
+}
@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"math"
+
+	hal "../core"
+)
+
+// A simplified version of the official "SolarSystem" generator. What matters to the bots:
+//
+//   - Ships start where the official engine puts them.
+//   - Planets 0-3 are the small centre planets (the bot relies on this).
+//   - The map is symmetric: point symmetry in 2 player games, mirror symmetry in 4 player games.
+
+func (self *Game) spawn_points() []*hal.Point {
+
+	w, h := float64(self.width), float64(self.height)
+
+	if len(self.stats) == 2 {
+		return []*hal.Point{
+			&hal.Point{X: w / 2, Y: h / 4},
+			&hal.Point{X: w / 2, Y: h * 3 / 4},
+		}
+	}
+
+	return []*hal.Point{
+		&hal.Point{X: w / 4, Y: h / 4},
+		&hal.Point{X: w * 3 / 4, Y: h / 4},
+		&hal.Point{X: w / 4, Y: h * 3 / 4},
+		&hal.Point{X: w * 3 / 4, Y: h * 3 / 4},
+	}
+}
+
+func (self *Game) generate_ships() {
+	for pid, point := range self.spawn_points() {
+		self.add_ship(pid, point.X, point.Y)
+		self.add_ship(pid, point.X, point.Y - 3)
+		self.add_ship(pid, point.X, point.Y + 3)
+	}
+}
+
+func (self *Game) generate_planets() {
+
+	w, h := float64(self.width), float64(self.height)
+	cx, cy := w / 2, h / 2
+
+	// The centre planets...
+
+	r := 4.5 + self.rng.Float64() * 2
+	d := r * 1.55
+
+	self.add_planet(cx + d, cy + d, r)
+	self.add_planet(cx - d, cy + d, r)
+	self.add_planet(cx - d, cy - d, r)
+	self.add_planet(cx + d, cy - d, r)
+
+	// Then symmetric groups. Each placement gives 2 planets (2p) or 4 planets (4p)...
+
+	placements := 4; if len(self.stats) == 4 { placements = 5 }
+
+	for n := 0; n < placements; n++ {
+
+		for attempt := 0; attempt < 1000; attempt++ {
+
+			r := 5 + self.rng.Float64() * 9
+			x := r + 10 + self.rng.Float64() * (w - 2 * r - 20)
+			y := r + 10 + self.rng.Float64() * (h - 2 * r - 20)
+
+			var group [][2]float64
+
+			if len(self.stats) == 2 {
+				group = [][2]float64{{x, y}, {w - x, h - y}}
+			} else {
+				group = [][2]float64{{x, y}, {w - x, y}, {x, h - y}, {w - x, h - y}}
+			}
+
+			if self.group_fits(group, r) {
+				for _, pos := range group {
+					self.add_planet(pos[0], pos[1], r)
+				}
+				break
+			}
+		}
+	}
+}
+
+func (self *Game) group_fits(group [][2]float64, r float64) bool {
+
+	const (
+		PLANET_GAP = 6.0			// Between the edges of any two planets
+		SPAWN_GAP = 12.0			// Between a planet's edge and a starting ship
+	)
+
+	for i, pos := range group {
+
+		// The members of a group mustn't touch each other either...
+
+		for _, other := range group[i+1:] {
+			if hal.Dist(pos[0], pos[1], other[0], other[1]) < r * 2 + PLANET_GAP {
+				return false
+			}
+		}
+
+		for _, planet := range self.planets {
+			if hal.Dist(pos[0], pos[1], planet.X, planet.Y) < r + planet.Radius + PLANET_GAP {
+				return false
+			}
+		}
+
+		for _, point := range self.spawn_points() {
+			if hal.Dist(pos[0], pos[1], point.X, point.Y) < r + SPAWN_GAP {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (self *Game) add_planet(x, y, r float64) *Planet {
+	planet := &Planet{
+		Id: len(self.planets),
+		X: x,
+		Y: y,
+		HP: int(math.Round(r * HEALTH_PER_RADIUS)),
+		Radius: r,
+		DockingSpots: int(math.Ceil(r / 3)),
+		RemainingProduction: int(r * RESOURCES_PER_RADIUS),
+		Owner: -1,
+	}
+	self.planets[planet.Id] = planet
+	return planet
+}
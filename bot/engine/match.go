@@ -0,0 +1,229 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	hal "../core"
+	rep "../replay"
+)
+
+type MatchConfig struct {
+	Seed				int64
+	Width				int				// 0 means choose from seed
+	Height				int
+	InitTimeout			time.Duration	// <= 0 means no limit
+	TurnTimeout			time.Duration
+	Log					func(format_string string, args ...interface{})		// Optional
+	ReplayFile			string			// Optional: where to save a replay of the game
+}
+
+func DefaultMatchConfig(seed int64) MatchConfig {
+	return MatchConfig{
+		Seed: seed,
+		InitTimeout: 60 * time.Second,
+		TurnTimeout: 2 * time.Second,
+	}
+}
+
+type Result struct {
+	Seed				int64
+	Width				int
+	Height				int
+	Turns				int
+	Ranks				[]int			// Indexed by player ID. 1 is the winner.
+	Stats				[]PlayerStats
+}
+
+func (self *Result) Winner() int {
+	for pid, rank := range self.Ranks {
+		if rank == 1 {
+			return pid
+		}
+	}
+	return -1
+}
+
+// RunMatch plays a whole game between the bots (2 or 4 of them). The bots are closed afterwards.
+// If the game is played but its replay can't be saved, both the result and an error are returned.
+
+func RunMatch(bots []Bot, config MatchConfig) (*Result, error) {
+
+	defer func() {
+		for _, bot := range bots {
+			bot.Close()
+		}
+	}()
+
+	log := config.Log
+	if log == nil {
+		log = func(string, ...interface{}) {}
+	}
+
+	game, err := NewGame(len(bots), config.Seed, config.Width, config.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	// Init stage: each bot gets its ID, the map size and the first frame; and replies with its name...
+
+	for pid, bot := range bots {
+		err := bot.Send(game.InitString(pid))
+		if err != nil {
+			game.Kick(pid, fmt.Sprintf("init send failed: %v", err))
+		}
+	}
+
+	names, init_times := receive_all(game, bots, config.InitTimeout)
+
+	for pid, name := range names {
+		if name != nil {
+			game.SetName(pid, *name)
+		}
+	}
+
+	var player_names []string
+	for pid := 0; pid < game.Players(); pid++ {
+		player_names = append(player_names, game.Stats(pid).Name)
+	}
+
+	replay := rep.NewWriter(game.ReplayHeader(player_names))
+	replay.AddFrame(game.ReplayFrame())
+
+	turn_times := make([][]time.Duration, len(bots))
+
+	// Main loop...
+
+	for game.Over() == false {
+
+		frame := game.FrameString()
+
+		for _, pid := range game.AlivePlayers() {
+			err := bots[pid].Send(frame)
+			if err != nil {
+				game.Kick(pid, fmt.Sprintf("send failed on turn %d: %v", game.Turn(), err))
+			}
+		}
+
+		lines, times := receive_all(game, bots, config.TurnTimeout)
+
+		commands := make([][]hal.Order, len(bots))
+		var moves []*rep.Move
+
+		for pid, line := range lines {
+			if line == nil {
+				continue
+			}
+			cmds, err := hal.ParseOrders(*line)
+			if err != nil {
+				game.Kick(pid, fmt.Sprintf("turn %d: %v", game.Turn(), err))
+				continue
+			}
+			commands[pid] = cmds
+			moves = append(moves, ReplayMoves(pid, cmds)...)
+			turn_times[pid] = append(turn_times[pid], times[pid])
+		}
+
+		for _, complaint := range game.Step(commands) {
+			log("t %3d: %s", game.Turn() - 1, complaint)
+		}
+
+		replay.AddMoves(moves)
+		replay.AddFrame(game.ReplayFrame())
+	}
+
+	result := &Result{
+		Seed: game.Seed(),
+		Width: game.Width(),
+		Height: game.Height(),
+		Turns: game.Turn(),
+		Ranks: game.Ranks(),
+	}
+
+	for pid := 0; pid < game.Players(); pid++ {
+		result.Stats = append(result.Stats, game.Stats(pid))
+		if result.Stats[pid].Error != "" {
+			log("Player %d (%s) was kicked: %s", pid, result.Stats[pid].Name, result.Stats[pid].Error)
+		}
+	}
+
+	if config.ReplayFile != "" {
+		replay.Finish(replay_stats(game, result.Ranks, init_times, turn_times))
+		err := replay.Save(config.ReplayFile)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func replay_stats(game *Game, ranks []int, init_times []time.Duration, turn_times [][]time.Duration) []*rep.PlayerStats {
+
+	var ret []*rep.PlayerStats
+
+	for pid := 0; pid < game.Players(); pid++ {
+
+		stats := game.Stats(pid)
+
+		s := &rep.PlayerStats{
+			Rank: ranks[pid],
+			LastFrameAlive: stats.LastFrameAlive,
+			TotalShipCount: len(game.ShipsOwnedBy(pid)),
+			DamageDealt: stats.DamageDealt,
+			InitResponseTime: milliseconds(init_times[pid]),
+		}
+
+		for _, t := range turn_times[pid] {
+			s.AverageResponseTime += milliseconds(t) / float64(len(turn_times[pid]))
+			s.MaxResponseTime = math.Max(s.MaxResponseTime, milliseconds(t))
+		}
+
+		ret = append(ret, s)
+	}
+
+	return ret
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// receive_all gets one line from every living bot, concurrently, so each has its full time allowance.
+// Bots that fail are kicked and get a nil entry. Also returns how long each bot took.
+
+func receive_all(game *Game, bots []Bot, timeout time.Duration) ([]*string, []time.Duration) {
+
+	ret := make([]*string, len(bots))
+	errs := make([]error, len(bots))
+	times := make([]time.Duration, len(bots))
+
+	var wg sync.WaitGroup
+
+	for _, pid := range game.AlivePlayers() {
+		wg.Add(1)
+		go func(pid int) {
+			defer wg.Done()
+			start := time.Now()
+			line, err := bots[pid].Receive(timeout)
+			times[pid] = time.Since(start)
+			if err != nil {
+				errs[pid] = err
+			} else {
+				ret[pid] = &line
+			}
+		}(pid)
+	}
+
+	wg.Wait()
+
+	for pid, err := range errs {
+		if err != nil {
+			game.Kick(pid, fmt.Sprintf("turn %d: %v", game.Turn(), err))
+		}
+	}
+
+	return ret, times
+}
@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Bot is anything that speaks the Halite protocol: we send it frames, it sends back lines.
+
+type Bot interface {
+	Send(s string) error
+	Receive(timeout time.Duration) (string, error)		// timeout <= 0 means wait forever
+	Close()
+}
+
+// ---------------------------------------
+
+type StreamBot struct {
+	out					io.Writer
+	lines				chan string
+	read_err			error						// Only valid once lines is closed
+}
+
+func NewStreamBot(to_bot io.Writer, from_bot io.Reader) *StreamBot {
+
+	ret := new(StreamBot)
+	ret.out = to_bot
+	ret.lines = make(chan string, 1)
+
+	go func() {
+		reader := bufio.NewReader(from_bot)
+		for {
+			line, err := reader.ReadString('\n')
+			if len(line) > 0 || err == nil {
+				ret.lines <- strings.TrimRight(line, "\r\n")
+			}
+			if err != nil {
+				ret.read_err = err
+				close(ret.lines)
+				return
+			}
+		}
+	}()
+
+	return ret
+}
+
+func (self *StreamBot) Send(s string) error {
+	_, err := io.WriteString(self.out, s)
+	return err
+}
+
+func (self *StreamBot) Receive(timeout time.Duration) (string, error) {
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		deadline = time.After(timeout)
+	}
+
+	select {
+	case line, ok := <-self.lines:
+		if ok == false {
+			return "", fmt.Errorf("bot output ended: %v", self.read_err)
+		}
+		return line, nil
+	case <-deadline:
+		return "", fmt.Errorf("timed out after %v", timeout)
+	}
+}
+
+func (self *StreamBot) Close() {
+	if closer, ok := self.out.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// ---------------------------------------
+
+type ProcessBot struct {
+	*StreamBot
+	cmd					*exec.Cmd
+}
+
+// NewProcessBot starts a bot from a command line, e.g. "./MyBot -conservative". The bot's stderr is passed through.
+
+func NewProcessBot(command string) (*ProcessBot, error) {
+	return NewProcessBotIn(command, "")
+}
+
+// NewProcessBotIn is NewProcessBot with the bot running in the given working directory ("" for
+// ours), so that bots playing at the same time don't write over each other's logs. Any part of
+// the command that names an existing file (e.g. "./MyBot" or "old.json") is made absolute first,
+// so it still works from there.
+
+func NewProcessBotIn(command, dir string) (*ProcessBot, error) {
+
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("NewProcessBot(): empty command")
+	}
+
+	if dir != "" {
+		for i, field := range fields {
+			if strings.HasPrefix(field, "-") || filepath.IsAbs(field) {
+				continue
+			}
+			if _, err := os.Stat(field); err == nil {
+				abs, err := filepath.Abs(field)
+				if err == nil {
+					fields[i] = abs
+				}
+			}
+		}
+	}
+
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stderr = os.Stderr
+	cmd.Dir = dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProcessBot{NewStreamBot(stdin, stdout), cmd}, nil
+}
+
+func (self *ProcessBot) Close() {
+	self.StreamBot.Close()
+	if self.cmd.Process != nil {
+		self.cmd.Process.Kill()
+	}
+	self.cmd.Wait()
+}
+
+// ---------------------------------------
+
+type InProcessBot struct {
+	*StreamBot
+	queue				chan string
+	mutex				sync.Mutex					// Guards closed, so Send() never sends on a closed queue
+	closed				bool
+}
+
+// NewInProcessBot runs a bot as a goroutine in this process. The function is given the
+// bot's input and output streams, e.g. it might call core.ReadGame(r, w) and then loop
+// until Parse() fails (see players_test.go). When the match ends the input is closed, so
+// it will; a bot that panics instead (as MyBot does) is recovered from here.
+
+func NewInProcessBot(run func(r io.Reader, w io.Writer)) *InProcessBot {
+
+	to_bot_r, to_bot_w := io.Pipe()
+	from_bot_r, from_bot_w := io.Pipe()
+
+	go func() {
+		defer func() {
+			p := recover()
+			if p != nil {
+				from_bot_w.CloseWithError(fmt.Errorf("bot panicked: %v", p))
+			} else {
+				from_bot_w.Close()
+			}
+			to_bot_r.Close()
+		}()
+		run(to_bot_r, from_bot_w)
+	}()
+
+	// Writes to a pipe block until the reader takes the data, which would stall the
+	// match if the bot was still busy with an earlier turn. So we queue instead.
+
+	ret := &InProcessBot{
+		StreamBot: NewStreamBot(to_bot_w, from_bot_r),
+		queue: make(chan string, MAX_TURNS + 2),
+	}
+
+	go func() {
+		for s := range ret.queue {
+			_, err := io.WriteString(to_bot_w, s)
+			if err != nil {
+				break
+			}
+		}
+		to_bot_w.Close()
+		for range ret.queue {}					// Drain anything sent after a write error
+	}()
+
+	return ret
+}
+
+func (self *InProcessBot) Send(s string) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.closed {
+		return fmt.Errorf("Send(): bot is closed")
+	}
+	self.queue <- s
+	return nil
+}
+
+func (self *InProcessBot) Close() {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.closed == false {
+		close(self.queue)
+		self.closed = true
+	}
+}
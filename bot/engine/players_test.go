@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	ai "../ai"
+	hal "../core"
+)
+
+// in_process_mybot is MyBot's main loop, minus the logging and flags, for NewInProcessBot().
+
+func in_process_mybot(r io.Reader, w io.Writer) {
+
+	game, err := hal.ReadGame(r, w)
+	if err != nil {
+		return
+	}
+
+	game.Budget().SetLimit(10 * time.Millisecond)		// Keeps the test quick; the GA just settles sooner.
+
+	fmt.Fprintf(w, "InProcessBot\n")
+
+	overmind := ai.NewOvermind(game, new(ai.Config))
+
+	for {
+		if game.Parse() != nil {
+			return										// The match is over and our input was closed.
+		}
+		overmind.StepSafely()
+		game.Send(false)
+	}
+}
+
+func TestInProcessMatch(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipped in -short mode")
+	}
+
+	config := DefaultMatchConfig(42)
+	config.Width = 160
+	config.Height = 120
+
+	bots := []Bot{
+		NewInProcessBot(in_process_mybot),
+		NewInProcessBot(in_process_mybot),
+	}
+
+	result, err := RunMatch(bots, config)
+	if err != nil {
+		t.Fatalf("RunMatch(): %v", err)
+	}
+
+	for pid, stats := range result.Stats {
+		if stats.Error != "" {
+			t.Errorf("player %d was kicked: %s", pid, stats.Error)
+		}
+		if stats.Name != "InProcessBot" {
+			t.Errorf("player %d is called \"%s\"", pid, stats.Name)
+		}
+	}
+
+	if result.Winner() < 0 || result.Winner() >= len(bots) {
+		t.Errorf("bad winner %d", result.Winner())
+	}
+
+	if result.Turns == 0 {
+		t.Errorf("no turns were played")
+	}
+}
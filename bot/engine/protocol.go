@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	hal "../core"
+)
+
+// The exact format read by core.Game.Parse(). Deprecated fields (ship velocity, cooldown) are sent as 0.
+
+func (self *Game) InitString(pid int) string {
+	return fmt.Sprintf("%d\n%d %d\n%s", pid, self.width, self.height, self.FrameString())
+}
+
+func (self *Game) FrameString() string {
+
+	var tokens []string
+
+	add := func(format string, args ...interface{}) {
+		tokens = append(tokens, fmt.Sprintf(format, args...))
+	}
+
+	add("%d", len(self.stats))
+
+	for pid := range self.stats {
+
+		ships := self.ShipsOwnedBy(pid)
+
+		add("%d %d", pid, len(ships))
+
+		for _, ship := range ships {
+			docked_planet := ship.DockedPlanet; if docked_planet == -1 { docked_planet = 0 }
+			add("%d %s %s %d 0 0 %d %d %d 0",
+				ship.Id, float_token(ship.X), float_token(ship.Y), ship.HP, int(ship.DockedStatus), docked_planet, ship.DockingProgress)
+		}
+	}
+
+	planets := self.AllPlanets()
+
+	add("%d", len(planets))
+
+	for _, planet := range planets {
+
+		owned, owner := 0, 0
+		if planet.Owner != -1 {
+			owned, owner = 1, planet.Owner
+		}
+
+		add("%d %s %s %d %s %d %d %d %d %d %d",
+			planet.Id, float_token(planet.X), float_token(planet.Y), planet.HP, float_token(planet.Radius),
+			planet.DockingSpots, planet.CurrentProduction, planet.RemainingProduction, owned, owner, len(planet.Docked))
+
+		for _, sid := range planet.Docked {
+			add("%d", sid)
+		}
+	}
+
+	return strings.Join(tokens, " ") + "\n"
+}
+
+func float_token(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
+
+// ---------------------------------------
+
+// Orders are read with core.ParseOrders(). A bad order for a particular ship (e.g. not the
+// player's ship) is ignored later, by Step().
+
+func order_velocity(cmd hal.Order) (float64, float64) {
+	speed := hal.Min(hal.Max(cmd.Speed, 0), hal.MAX_SPEED)
+	return hal.Projection(0, 0, float64(speed), cmd.RawAngle())		// The real engine uses the raw angle too, messages and all.
+}
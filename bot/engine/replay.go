@@ -0,0 +1,188 @@
+package engine
+
+import (
+	hal "../core"
+	rep "../replay"
+)
+
+// Conversion of the game state to the replay format, so RunMatch (or any other harness
+// stepping a Game) can feed a replay.Writer.
+
+func (self *Game) ReplayHeader(names []string) rep.Replay {
+
+	ret := rep.Replay{
+		MapGenerator: "gohalite2",
+		Seed: self.seed,
+		Width: self.width,
+		Height: self.height,
+		NumPlayers: self.Players(),
+		PlayerNames: names,
+		Constants: ReplayConstants(),
+	}
+
+	for _, planet := range self.AllPlanets() {
+		ret.Planets = append(ret.Planets, &rep.PlanetInfo{
+			Id: planet.Id,
+			X: planet.X,
+			Y: planet.Y,
+			Radius: planet.Radius,
+			DockingSpots: planet.DockingSpots,
+			Health: planet.HP,
+			Production: planet.RemainingProduction,
+		})
+	}
+
+	return ret
+}
+
+func ReplayConstants() rep.Constants {
+	return rep.Constants{
+		AdditionalProductivity: hal.ADDITIONAL_PRODUCTIVITY,
+		BaseProductivity: hal.BASE_PRODUCTIVITY,
+		BaseShipHealth: hal.MAX_SHIP_HEALTH,
+		DockRadius: hal.DOCKING_RADIUS,
+		DockTurns: hal.DOCK_TURNS,
+		Drag: 10,								// Meaningless to us, but as in the official config
+		ExtraPlanets: 4,
+		ExplosionRadius: hal.EXPLOSION_RADIUS,
+		InfiniteResources: true,
+		MaxAcceleration: hal.MAX_SPEED,
+		MaxShipHealth: hal.MAX_SHIP_HEALTH,
+		MaxSpeed: hal.MAX_SPEED,
+		MaxTurns: MAX_TURNS,
+		PlanetsPerPlayer: 6,
+		ProductionPerShip: hal.PRODUCTION_PER_SHIP,
+		ResourcesPerRadius: RESOURCES_PER_RADIUS,
+		ShipsPerPlayer: SHIPS_PER_PLAYER,
+		ShipRadius: hal.SHIP_RADIUS,
+		SpawnRadius: hal.SPAWN_RADIUS,
+		WeaponCooldown: 1,
+		WeaponDamage: hal.WEAPON_DAMAGE,
+		WeaponRadius: hal.WEAPON_RANGE,
+	}
+}
+
+// ReplayFrame is the current state, plus the events of the last Step().
+
+func (self *Game) ReplayFrame() *rep.Frame {
+
+	ret := &rep.Frame{Events: self.events}
+
+	for _, ship := range self.AllShips() {
+
+		s := &rep.Ship{
+			Id: ship.Id,
+			Owner: ship.Owner,
+			X: ship.X,
+			Y: ship.Y,
+			HP: ship.HP,
+			DockedStatus: ship.DockedStatus,
+			DockedPlanet: ship.DockedPlanet,
+		}
+
+		if ship.DockedStatus == hal.DOCKING || ship.DockedStatus == hal.UNDOCKING {
+			s.TurnsLeft = ship.DockingProgress
+		}
+
+		ret.Ships = append(ret.Ships, s)
+	}
+
+	for _, planet := range self.AllPlanets() {
+		ret.Planets = append(ret.Planets, &rep.Planet{
+			Id: planet.Id,
+			HP: planet.HP,
+			Owner: planet.Owner,
+			DockedShips: append([]int(nil), planet.Docked...),
+			CurrentProduction: planet.CurrentProduction,
+			RemainingProduction: planet.RemainingProduction,
+		})
+	}
+
+	return ret
+}
+
+func ReplayMoves(pid int, cmds []hal.Order) []*rep.Move {
+
+	var ret []*rep.Move
+
+	for _, cmd := range cmds {
+
+		m := &rep.Move{Owner: pid, ShipId: cmd.Sid}
+
+		switch cmd.Type {
+		case hal.THRUST:
+			m.Type, m.Magnitude, m.Angle = rep.THRUST, cmd.Speed, cmd.RawAngle()
+		case hal.DOCK:
+			m.Type, m.PlanetId = rep.DOCK, cmd.Plid
+		case hal.UNDOCK:
+			m.Type = rep.UNDOCK
+		}
+
+		ret = append(ret, m)
+	}
+
+	return ret
+}
+
+// ---------------------------------------
+
+func ship_ref(ship *Ship) rep.EntityRef {
+	return rep.EntityRef{Type: "ship", Id: ship.Id, Owner: ship.Owner}
+}
+
+func planet_ref(planet *Planet) rep.EntityRef {
+	return rep.EntityRef{Type: "planet", Id: planet.Id, Owner: -1}
+}
+
+func (self *Game) record_attack(shooter *Ship, targets []*Ship, t float64) {
+
+	ev := &rep.Event{
+		Type: rep.ATTACK,
+		Time: t,
+		X: shooter.X + shooter.vel_x * t,
+		Y: shooter.Y + shooter.vel_y * t,
+		Entity: ship_ref(shooter),
+	}
+
+	for _, target := range targets {
+		ev.Targets = append(ev.Targets, ship_ref(target))
+	}
+
+	self.events = append(self.events, ev)
+}
+
+// The recorded position is where the ship is at time t, given its velocity (if any is still set).
+
+func (self *Game) record_ship_destroyed(ship *Ship, t float64) {
+	self.events = append(self.events, &rep.Event{
+		Type: rep.DESTROYED,
+		Time: t,
+		X: ship.X + ship.vel_x * t,
+		Y: ship.Y + ship.vel_y * t,
+		Entity: ship_ref(ship),
+		Radius: hal.SHIP_RADIUS,
+	})
+}
+
+func (self *Game) record_planet_destroyed(planet *Planet, t float64) {
+	self.events = append(self.events, &rep.Event{
+		Type: rep.DESTROYED,
+		Time: t,
+		X: planet.X,
+		Y: planet.Y,
+		Entity: planet_ref(planet),
+		Radius: planet.Radius,
+	})
+}
+
+func (self *Game) record_spawn(ship *Ship, planet *Planet) {
+	self.events = append(self.events, &rep.Event{
+		Type: rep.SPAWNED,
+		X: ship.X,
+		Y: ship.Y,
+		Entity: ship_ref(ship),
+		Planet: planet_ref(planet),
+		PlanetX: planet.X,
+		PlanetY: planet.Y,
+	})
+}
@@ -0,0 +1,485 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+
+	hal "../core"
+)
+
+// Step resolves one turn. The order of things follows the official engine (as far as can be
+// told from replays):
+//
+//   1. Docking / undocking progress.
+//   2. Orders are applied (velocities set, dock / undock commands started). Docks are resolved
+//      per planet once every player's orders are in, so a neutral planet can be contested.
+//   3. Movement is simulated continuously over the turn, with collisions, combat and planet explosions.
+//   4. Production and ship spawning.
+//
+// commands is indexed by player ID. Returns a list of complaints about orders that were ignored.
+
+func (self *Game) Step(commands [][]hal.Order) []string {
+
+	var complaints []string
+
+	self.events = nil
+
+	self.process_docking()
+
+	dock_requests := make(map[int][]dock_request)
+
+	for pid, cmds := range commands {
+		if pid < len(self.stats) && self.stats[pid].Alive {
+			complaints = append(complaints, self.apply_commands(pid, cmds, dock_requests)...)
+		}
+	}
+
+	complaints = append(complaints, self.resolve_docks(dock_requests)...)
+
+	self.process_movement()
+	self.process_production()
+
+	self.turn++
+
+	for pid, stats := range self.stats {
+		if stats.Alive {
+			if len(self.ShipsOwnedBy(pid)) == 0 {
+				stats.Alive = false
+			} else {
+				stats.LastFrameAlive = self.turn
+			}
+		}
+	}
+
+	return complaints
+}
+
+// ---------------------------------------
+
+func (self *Game) process_docking() {
+
+	for _, ship := range self.AllShips() {
+
+		switch ship.DockedStatus {
+
+		case hal.DOCKING:
+
+			ship.DockingProgress--
+			if ship.DockingProgress <= 0 {
+				ship.DockingProgress = 0
+				ship.DockedStatus = hal.DOCKED
+			}
+
+		case hal.UNDOCKING:
+
+			ship.DockingProgress--
+			if ship.DockingProgress <= 0 {
+				planet, ok := self.planets[ship.DockedPlanet]
+				if ok {
+					planet.remove_docked(ship.Id)
+				}
+				ship.DockingProgress = 0
+				ship.DockedStatus = hal.UNDOCKED
+				ship.DockedPlanet = -1
+			}
+		}
+	}
+}
+
+func (self *Game) process_production() {
+
+	for _, planet := range self.AllPlanets() {
+
+		if planet.Owner == -1 {
+			continue
+		}
+
+		docked := 0
+
+		for _, sid := range planet.Docked {
+			if self.ships[sid].DockedStatus == hal.DOCKED {
+				docked++
+			}
+		}
+
+		if docked == 0 {
+			continue
+		}
+
+		planet.CurrentProduction += hal.Production(docked)
+
+		for planet.CurrentProduction >= hal.PRODUCTION_PER_SHIP {
+
+			x, y, ok := self.spawn_location(planet)
+			if ok == false {
+				break
+			}
+
+			planet.CurrentProduction -= hal.PRODUCTION_PER_SHIP
+			ship := self.add_ship(planet.Owner, x, y)
+			self.record_spawn(ship, planet)
+			self.stats[planet.Owner].ShipsProduced++
+		}
+	}
+}
+
+func (self *Game) spawn_location(planet *Planet) (float64, float64, bool) {
+
+	occupied := func(x, y float64) bool {
+		for _, ship := range self.ships {
+			if hal.Dist(x, y, ship.X, ship.Y) <= hal.SPAWN_FREE_DIST {
+				return true
+			}
+		}
+		return false
+	}
+
+	return hal.SpawnLocation(planet.X, planet.Y, planet.Radius, self.width, self.height, occupied)
+}
+
+// ---------------------------------------
+
+type dock_request struct {
+	pid					int
+	ship				*Ship
+	cmd					hal.Order
+}
+
+func make_complaint(pid int, cmd hal.Order, why string) string {
+	return fmt.Sprintf("player %d: ignored \"%s\": %s", pid, cmd.Wire(false), why)
+}
+
+// apply_commands applies one player's orders. Docks that pass the checks which only concern the
+// ship itself are added to dock_requests (planet ID --> requests), to be settled by resolve_docks().
+
+func (self *Game) apply_commands(pid int, cmds []hal.Order, dock_requests map[int][]dock_request) []string {
+
+	var complaints []string
+
+	complain := func(cmd hal.Order, why string) {
+		complaints = append(complaints, make_complaint(pid, cmd, why))
+	}
+
+	seen := make(map[int]bool)
+
+	for _, cmd := range cmds {
+
+		ship, ok := self.ships[cmd.Sid]
+
+		if ok == false || ship.Owner != pid {
+			complain(cmd, "not our ship")
+			continue
+		}
+
+		if seen[cmd.Sid] {
+			complain(cmd, "ship already has an order")
+			continue
+		}
+
+		seen[cmd.Sid] = true
+
+		switch cmd.Type {
+
+		case hal.THRUST:
+
+			if ship.DockedStatus != hal.UNDOCKED {
+				complain(cmd, "ship is docked")
+				continue
+			}
+
+			if cmd.Speed > hal.MAX_SPEED || cmd.Speed < 0 {
+				complain(cmd, "bad speed (clamped)")
+			}
+
+			ship.vel_x, ship.vel_y = order_velocity(cmd)
+
+		case hal.DOCK:
+
+			if ship.DockedStatus != hal.UNDOCKED {
+				complain(cmd, "ship is docked")
+				continue
+			}
+
+			planet, ok := self.planets[cmd.Plid]
+
+			if ok == false {
+				complain(cmd, "no such planet")
+				continue
+			}
+
+			if hal.InDockingRange(ship.X, ship.Y, planet.X, planet.Y, planet.Radius) == false {
+				complain(cmd, "too far from planet")
+				continue
+			}
+
+			dock_requests[planet.Id] = append(dock_requests[planet.Id], dock_request{pid, ship, cmd})
+
+		case hal.UNDOCK:
+
+			if ship.DockedStatus != hal.DOCKED {
+				complain(cmd, "ship is not fully docked")
+				continue
+			}
+
+			ship.DockedStatus = hal.UNDOCKING
+			ship.DockingProgress = hal.DOCK_TURNS
+		}
+	}
+
+	return complaints
+}
+
+// resolve_docks settles the turn's docks, planet by planet, by the rules in hal.ResolveDocks().
+
+func (self *Game) resolve_docks(dock_requests map[int][]dock_request) []string {
+
+	var complaints []string
+
+	var plids []int
+	for plid, _ := range dock_requests {
+		plids = append(plids, plid)
+	}
+	sort.Ints(plids)
+
+	for _, plid := range plids {
+
+		planet := self.planets[plid]
+
+		var rules_requests []*hal.DockRequest
+		for _, req := range dock_requests[plid] {
+			rules_requests = append(rules_requests, &hal.DockRequest{Pid: req.pid, Sid: req.ship.Id})
+		}
+
+		planet.Owner = hal.ResolveDocks(planet.Owner, planet.DockingSpots - len(planet.Docked), rules_requests)
+
+		for i, req := range dock_requests[plid] {
+
+			if rules_requests[i].Refusal != "" {
+				complaints = append(complaints, make_complaint(req.pid, req.cmd, rules_requests[i].Refusal))
+				continue
+			}
+
+			planet.Docked = append(planet.Docked, req.ship.Id)
+
+			req.ship.DockedStatus = hal.DOCKING
+			req.ship.DockedPlanet = planet.Id
+			req.ship.DockingProgress = hal.DOCK_TURNS
+		}
+	}
+
+	return complaints
+}
+
+// ---------------------------------------
+
+type event_type int
+
+const (
+	ATTACK_EVENT event_type = iota
+	COLLISION_EVENT
+	PLANET_EVENT
+)
+
+type event struct {
+	t					float64
+	what				event_type
+	ship_a				*Ship
+	ship_b				*Ship
+	planet				*Planet
+}
+
+func (self *Game) process_movement() {
+
+	ships := self.AllShips()
+	planets := self.AllPlanets()
+
+	var events []*event
+
+	for i, ship_a := range ships {
+
+		for _, ship_b := range ships[i+1:] {
+
+			if ship_a.Owner != ship_b.Owner {
+				t, ok := collision_time(hal.ATTACK_RANGE, ship_a, ship_b)
+				if ok && t >= 0 && t <= 1 {
+					events = append(events, &event{t, ATTACK_EVENT, ship_a, ship_b, nil})
+				}
+			}
+
+			t, ok := collision_time(hal.SHIP_RADIUS * 2, ship_a, ship_b)
+			if ok && t >= 0 && t <= 1 {
+				events = append(events, &event{t, COLLISION_EVENT, ship_a, ship_b, nil})
+			}
+		}
+
+		for _, planet := range planets {
+			t, ok := collision_time(planet.Radius + hal.SHIP_RADIUS, ship_a, &Ship{X: planet.X, Y: planet.Y})
+			if ok && t >= 0 && t <= 1 {
+				events = append(events, &event{t, PLANET_EVENT, ship_a, nil, planet})
+			}
+		}
+	}
+
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].t < events[b].t
+	})
+
+	// Events at the same moment are simultaneous: nothing dies until the whole group is handled.
+
+	for i := 0; i < len(events); {
+
+		j := i
+		for j < len(events) && events[j].t == events[i].t {
+			j++
+		}
+
+		self.resolve_events(events[i:j], ships)
+		i = j
+	}
+
+	for _, ship := range ships {
+
+		if ship.Alive() == false {
+			continue
+		}
+
+		ship.X += ship.vel_x
+		ship.Y += ship.vel_y
+		ship.vel_x, ship.vel_y = 0, 0
+		ship.fired = false
+
+		if ship.X < 0 || ship.Y < 0 || ship.X >= float64(self.width) || ship.Y >= float64(self.height) {
+			self.record_ship_destroyed(ship, 1)
+			self.destroy_ship(ship)
+		}
+	}
+}
+
+func (self *Game) resolve_events(group []*event, ships []*Ship) {
+
+	t := group[0].t
+
+	damage := make(map[*Ship]int)
+	planet_damage := make(map[*Planet]int)
+
+	for _, ev := range group {
+
+		switch ev.what {
+
+		case COLLISION_EVENT:
+
+			if ev.ship_a.Alive() && ev.ship_b.Alive() {
+				damage[ev.ship_a] += ev.ship_a.HP
+				damage[ev.ship_b] += ev.ship_b.HP
+			}
+
+		case PLANET_EVENT:
+
+			if ev.ship_a.Alive() && ev.planet.Alive() {
+				planet_damage[ev.planet] += ev.ship_a.HP
+				damage[ev.ship_a] += ev.ship_a.HP
+			}
+
+		case ATTACK_EVENT:
+
+			// Whoever can fire, fires at everything in range at this moment.
+
+			for _, shooter := range []*Ship{ev.ship_a, ev.ship_b} {
+
+				if shooter.Alive() == false || shooter.fired || shooter.DockedStatus != hal.UNDOCKED {
+					continue
+				}
+
+				var targets []*Ship
+
+				sx, sy := shooter.X + shooter.vel_x * t, shooter.Y + shooter.vel_y * t
+
+				for _, other := range ships {
+					if other.Alive() && other.Owner != shooter.Owner {
+						ox, oy := other.X + other.vel_x * t, other.Y + other.vel_y * t
+						if hal.Dist(sx, sy, ox, oy) <= hal.ATTACK_RANGE + 0.0001 {
+							targets = append(targets, other)
+						}
+					}
+				}
+
+				if len(targets) == 0 {
+					continue
+				}
+
+				shooter.fired = true
+				self.record_attack(shooter, targets, t)
+
+				for _, target := range targets {
+					damage[target] += hal.WEAPON_DAMAGE / len(targets)
+					self.stats[shooter.Owner].DamageDealt += hal.WEAPON_DAMAGE / len(targets)
+				}
+			}
+		}
+	}
+
+	for ship, dmg := range damage {
+		ship.HP -= dmg
+	}
+
+	for planet, dmg := range planet_damage {
+		planet.HP -= dmg
+	}
+
+	for _, ship := range ships {
+		if ship.HP <= 0 {
+			if _, ok := self.ships[ship.Id]; ok {
+				self.record_ship_destroyed(ship, t)
+				self.destroy_ship(ship)
+			}
+		}
+	}
+
+	for _, planet := range self.AllPlanets() {
+		if planet.HP <= 0 {
+			self.explode_planet(planet, t, ships)
+		}
+	}
+}
+
+func (self *Game) explode_planet(planet *Planet, t float64, ships []*Ship) {
+
+	for _, sid := range planet.Docked {
+		ship, ok := self.ships[sid]
+		if ok {
+			ship.DockedPlanet = -1				// So destroy_ship() doesn't touch the planet we're iterating over.
+			self.record_ship_destroyed(ship, t)
+			self.destroy_ship(ship)
+		}
+	}
+
+	planet.Docked = nil
+	planet.HP = 0
+	delete(self.planets, planet.Id)
+
+	self.record_planet_destroyed(planet, t)
+
+	// Damage falls off with distance from the planet's surface, see hal.ExplosionDamage()...
+
+	for _, ship := range ships {
+
+		if ship.Alive() == false {
+			continue
+		}
+
+		x, y := ship.X + ship.vel_x * t, ship.Y + ship.vel_y * t
+		dist := hal.Dist(x, y, planet.X, planet.Y) - planet.Radius
+
+		if dist < hal.EXPLOSION_RADIUS {
+			ship.HP -= hal.ExplosionDamage(dist)
+			if ship.HP <= 0 {
+				self.record_ship_destroyed(ship, t)
+				self.destroy_ship(ship)
+			}
+		}
+	}
+}
+
+func collision_time(r float64, e1 *Ship, e2 *Ship) (float64, bool) {
+	return hal.CollisionTime(r, e1.X - e2.X, e1.Y - e2.Y, e1.vel_x - e2.vel_x, e1.vel_y - e2.vel_y)
+}
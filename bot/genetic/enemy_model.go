@@ -0,0 +1,253 @@
+package genetic
+
+import (
+	"fmt"
+	"strings"
+
+	hal "../core"
+)
+
+// An EnemyModel guesses what an enemy ship will do this turn. Each model the Evolver is
+// given becomes one scenario in RunRushFight(), and the damage scored in that scenario
+// is weighted by how well the model has been predicting the enemy lately (see ModelTracker).
+
+type EnemyModel interface {
+	Name() string
+	Predict(game *hal.Game, ship *hal.Ship) (vel_x, vel_y float64)
+}
+
+// The models used before there was any choice: the enemy repeats its last move, or stays still.
+
+func DefaultEnemyModels() []EnemyModel {
+	return []EnemyModel{RepeatModel{}, StillModel{}}
+}
+
+// NewEnemyModel makes a built-in model from its name. The policy model needs a file, so
+// is made with LoadPolicyModel() instead.
+
+func NewEnemyModel(name string) (EnemyModel, error) {
+	switch name {
+	case "repeat":
+		return RepeatModel{}, nil
+	case "still":
+		return StillModel{}, nil
+	case "charge":
+		return ChargeModel{}, nil
+	case "flee":
+		return FleeModel{}, nil
+	case "mirror":
+		return MirrorModel{}, nil
+	}
+	return nil, fmt.Errorf("NewEnemyModel(): unknown model \"%s\"", name)
+}
+
+func ModelNames(models []EnemyModel) string {
+	var names []string
+	for _, model := range models {
+		names = append(names, model.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// --------------------------------------------------------------------
+
+type RepeatModel struct {}
+
+func (self RepeatModel) Name() string { return "repeat" }
+
+func (self RepeatModel) Predict(game *hal.Game, ship *hal.Ship) (float64, float64) {
+	return ship.Dx, ship.Dy
+}
+
+type StillModel struct {}
+
+func (self StillModel) Name() string { return "still" }
+
+func (self StillModel) Predict(game *hal.Game, ship *hal.Ship) (float64, float64) {
+	return 0, 0
+}
+
+// ChargeModel: the enemy heads for our nearest ship, stopping at weapon range.
+
+type ChargeModel struct {}
+
+func (self ChargeModel) Name() string { return "charge" }
+
+func (self ChargeModel) Predict(game *hal.Game, ship *hal.Ship) (float64, float64) {
+
+	target := nearest_ship_of(game.MyShips(), ship)
+	if target == nil {
+		return 0, 0
+	}
+
+	speed := hal.Min(hal.MAX_SPEED, hal.Max(0, int(ship.Dist(target) - hal.WEAPON_RANGE)))
+	return hal.Projection(0, 0, float64(speed), ship.Angle(target))
+}
+
+// FleeModel: the enemy runs directly away from our nearest ship.
+
+type FleeModel struct {}
+
+func (self FleeModel) Name() string { return "flee" }
+
+func (self FleeModel) Predict(game *hal.Game, ship *hal.Ship) (float64, float64) {
+
+	target := nearest_ship_of(game.MyShips(), ship)
+	if target == nil {
+		return 0, 0
+	}
+
+	return hal.Projection(0, 0, hal.MAX_SPEED, target.Angle(ship))
+}
+
+// MirrorModel: the enemy makes our nearest ship's last move, reflected through the centre of
+// the map (as it would be if it was playing the same bot from the other side of a 2 player map).
+
+type MirrorModel struct {}
+
+func (self MirrorModel) Name() string { return "mirror" }
+
+func (self MirrorModel) Predict(game *hal.Game, ship *hal.Ship) (float64, float64) {
+
+	target := nearest_ship_of(game.MyShips(), ship)
+	if target == nil {
+		return 0, 0
+	}
+
+	return -target.Dx, -target.Dy
+}
+
+func nearest_ship_of(ships []*hal.Ship, e hal.Entity) *hal.Ship {
+
+	var ret *hal.Ship
+	best_dist := 999999.9
+
+	for _, ship := range ships {
+		d := ship.Dist(e)
+		if d < best_dist {
+			ret, best_dist = ship, d
+		}
+	}
+
+	return ret
+}
+
+// --------------------------------------------------------------------
+
+// ModelTracker lives across turns. Each turn it checks the predictions it made last turn
+// against where the enemy ships actually went, then makes new predictions. Only enemy ships
+// near our own are tracked, since those are the ones the GA cares about.
+
+const (
+	TRACK_RANGE = 30
+)
+
+type vector struct {
+	x				float64
+	y				float64
+}
+
+type ModelTracker struct {
+	models			[]EnemyModel
+	memory			int								// How many turns of errors to remember
+	errors			[][]float64						// Model index --> mean error on recent turns, oldest first
+	predictions		map[int][]vector				// Ship ID --> predicted location by each model
+	predicted_turn	int
+}
+
+func NewModelTracker(models []EnemyModel, memory int) *ModelTracker {
+	ret := new(ModelTracker)
+	ret.models = models
+	ret.memory = memory
+	ret.errors = make([][]float64, len(models))
+	ret.predicted_turn = -1
+	return ret
+}
+
+func (self *ModelTracker) Models() []EnemyModel {
+	return self.models
+}
+
+func (self *ModelTracker) Update(game *hal.Game) {
+
+	if self.predictions != nil && self.predicted_turn == game.Turn() - 1 {
+
+		for m := range self.models {
+
+			total, count := 0.0, 0
+
+			for sid, predicted := range self.predictions {
+				ship, ok := game.GetShip(sid)
+				if ok {
+					total += hal.Dist(predicted[m].x, predicted[m].y, ship.X, ship.Y)
+					count++
+				}
+			}
+
+			if count > 0 {
+				self.errors[m] = append(self.errors[m], total / float64(count))
+				if len(self.errors[m]) > self.memory {
+					self.errors[m] = self.errors[m][1:]
+				}
+			}
+		}
+	}
+
+	self.predictions = make(map[int][]vector)
+	self.predicted_turn = game.Turn()
+
+	my_ships := game.MyShips()
+
+	for _, ship := range game.EnemyShips() {
+
+		if ship.CanMove() == false {
+			continue
+		}
+
+		nearest := nearest_ship_of(my_ships, ship)
+		if nearest == nil || nearest.Dist(ship) > TRACK_RANGE {
+			continue
+		}
+
+		for _, model := range self.models {
+			vel_x, vel_y := model.Predict(game, ship)
+			self.predictions[ship.Id] = append(self.predictions[ship.Id], vector{ship.X + vel_x, ship.Y + vel_y})
+		}
+	}
+}
+
+// Weights returns a weight for each model, higher for models with lower recent error. They
+// sum to the number of models, so with no history (or equal errors) every weight is 1.
+
+func (self *ModelTracker) Weights() []float64 {
+
+	ret := make([]float64, len(self.models))
+
+	for m := range self.models {
+		ret[m] = 1
+	}
+
+	for m := range self.models {
+		if len(self.errors[m]) == 0 {
+			return ret
+		}
+	}
+
+	total := 0.0
+
+	for m := range self.models {
+		mean := 0.0
+		for _, e := range self.errors[m] {
+			mean += e
+		}
+		mean /= float64(len(self.errors[m]))
+		ret[m] = 1 / (1 + mean)
+		total += ret[m]
+	}
+
+	for m := range self.models {
+		ret[m] *= float64(len(self.models)) / total
+	}
+
+	return ret
+}
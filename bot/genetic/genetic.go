@@ -43,21 +43,38 @@ func (self *Genome) Init(size int, randomise bool) {
 }
 
 func (self *Genome) Mutate() {
+	self.mutate(rand.Intn)
+}
+
+// MutateWith and RandomiseWith use the given RNG rather than the global one, so that
+// goroutines can each have their own and still get repeatable results.
+
+func (self *Genome) MutateWith(rng *rand.Rand) {
+	self.mutate(rng.Intn)
+}
+
+func (self *Genome) RandomiseWith(rng *rand.Rand) {
+	for _, gene := range self.genes {
+		gene.speed, gene.angle = rng.Intn(8), rng.Intn(360)
+	}
+}
+
+func (self *Genome) mutate(intn func(int) int) {
 
 	if len(self.genes) == 0 {
 		return
 	}
 
-	i := rand.Intn(len(self.genes))
+	i := intn(len(self.genes))
 
-	switch rand.Intn(3) {
+	switch intn(3) {
 	case 0:
-		self.genes[i].speed = rand.Intn(8)
+		self.genes[i].speed = intn(8)
 	case 1:
-		self.genes[i].angle = rand.Intn(360)
+		self.genes[i].angle = intn(360)
 	case 2:
-		self.genes[i].speed = rand.Intn(8)
-		self.genes[i].angle = rand.Intn(360)
+		self.genes[i].speed = intn(8)
+		self.genes[i].angle = intn(360)
 	}
 }
 
@@ -70,7 +87,13 @@ type Evolver struct {
 
 	game					*hal.Game
 	genomes					[]*Genome
-	genome_length			int
+	genome_length			int			// Number of mutable ships; the genome itself is this times the horizon.
+	horizon					int			// How many turns ahead each genome plans.
+
+	models					[]EnemyModel	// One scenario each, as well as the no-enemies scenario.
+	weights					[]float64		// How much each model's scenario counts.
+	punish_stupidity		[]bool			// Whether each model's scenario runs the stupidity checks.
+	model_vels				[][]vector		// Model index --> sim ship index --> velocity (enemies only).
 	sim						*Sim
 	sim_without_enemies		*Sim
 	first_enemy_index		int			// Doesn't mean we have enemies. Equal to number of friendlies (mutable or not) in the sim.
@@ -81,23 +104,28 @@ type Evolver struct {
 
 }
 
-func NewEvolver(game *hal.Game, my_mutable_ships, my_immutable_ships, enemy_ships []*hal.Ship, mc_chains int) *Evolver {
+func NewEvolver(game *hal.Game, my_mutable_ships, my_immutable_ships, enemy_ships []*hal.Ship, mc_chains, horizon int) *Evolver {
 
 	ret := new(Evolver)
 
 	ret.game = game
 
+	if horizon < 1 {
+		horizon = 1
+	}
+
+	ret.horizon = horizon
+	ret.genome_length = len(my_mutable_ships)
+
 	for n := 0; n < mc_chains; n++ {
 		ret.genomes = append(ret.genomes, new(Genome))
 		if n == 0 {
-			ret.genomes[n].Init(len(my_mutable_ships), false)
+			ret.genomes[n].Init(ret.genome_length * horizon, false)
 		} else {
-			ret.genomes[n].Init(len(my_mutable_ships), true)
+			ret.genomes[n].Init(ret.genome_length * horizon, true)
 		}
 	}
 
-	ret.genome_length = len(my_mutable_ships)
-
 	// We ensure our mutable ships are at the start of the baseSim's ships slice...
 
 	var relevant_ships []*hal.Ship
@@ -116,18 +144,63 @@ func NewEvolver(game *hal.Game, my_mutable_ships, my_immutable_ships, enemy_ship
 		}
 	}
 
+	ret.SetEnemyModels(DefaultEnemyModels(), nil)
+
 	return ret
 }
 
+// SetEnemyModels chooses what the enemy might do in RunRushFight(). Each model's prediction is
+// made once, here, and is repeated every turn if the horizon is longer than 1. A nil weights
+// slice gives the GA's original scoring: equal weights of 1, and our ships are only punished
+// for stupidity in the scenario where the enemy stays still. Otherwise every scenario counts.
+
+func (self *Evolver) SetEnemyModels(models []EnemyModel, weights []float64) {
+
+	self.punish_stupidity = make([]bool, len(models))
+
+	if weights == nil {
+		weights = make([]float64, len(models))
+		for m := range weights {
+			weights[m] = 1
+			_, still := models[m].(StillModel)
+			self.punish_stupidity[m] = still
+		}
+	} else {
+		for m := range self.punish_stupidity {
+			self.punish_stupidity[m] = true
+		}
+	}
+
+	self.models = models
+	self.weights = weights
+	self.model_vels = nil
+
+	for _, model := range models {
+
+		vels := make([]vector, len(self.sim.ships))
+
+		for i := self.first_enemy_index; i < len(self.sim.ships); i++ {
+			real_ship := self.sim.ships[i].real_ship
+			if real_ship.CanMove() {
+				vels[i].x, vels[i].y = model.Predict(self.game, real_ship)
+			}
+		}
+
+		self.model_vels = append(self.model_vels, vels)
+	}
+}
+
 func (self *Evolver) ExecuteGenome(msg int) {
 
-	for i, gene := range self.genomes[0].genes {
+	// Only the first turn of the plan is executed; next turn we evolve a new one.
+
+	for i, gene := range self.genomes[0].genes[:self.genome_length] {
 
 		real_ship := self.sim.ships[i].real_ship			// Relying on our mutable ships being stored first.
 
 		if real_ship.DockedStatus == hal.UNDOCKED {
 			self.game.Thrust(real_ship, gene.speed, gene.angle)
-			self.game.SetMessage(real_ship, msg)
+			self.game.SetMessage(real_ship, msg)			// After the order, since there must be one to attach it to.
 		}
 	}
 }
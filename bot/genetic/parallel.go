@@ -0,0 +1,159 @@
+package genetic
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+
+	hal "../core"
+)
+
+// RunRushFightParallel is RunRushFight() with the chains spread over several goroutines.
+//
+// Each chain has its own RNG, seeded from the seed and the chain's number. The chains only
+// meet every SWAP_INTERVAL iterations, when they are sorted by score (which is how hot and
+// cold chains swap places); in between they don't interact at all. So the result doesn't
+// depend on the number of workers, only on the seed and on when the clock runs out. In
+// deterministic mode the clock is ignored and all the iterations are run.
+
+const (
+	SWAP_INTERVAL = 50
+)
+
+type rush_chain struct {
+	genome			*Genome
+	backup			*Genome
+	rng				*rand.Rand
+	first_score		int				// Score before any mutation
+}
+
+func (self *Evolver) RunRushFightParallel(iterations int, play_perfect bool, workers int, seed int64, deterministic bool) {
+
+	real_enemy_ships := self.real_enemy_ships()
+
+	var chains []*rush_chain
+
+	for c, genome := range self.genomes {
+
+		chain := &rush_chain{
+			genome: genome,
+			backup: new(Genome),
+			rng: rand.New(rand.NewSource(seed + int64(c))),
+		}
+
+		chain.backup.Init(len(genome.genes), false)
+
+		if c > 0 {
+			genome.RandomiseWith(chain.rng)			// Replacing the randomisation NewEvolver() did with the global RNG.
+		}
+
+		chains = append(chains, chain)
+	}
+
+	workers = hal.Max(1, hal.Min(workers, len(chains)))
+
+	var pool []*rush_worker
+	for i := 0; i < workers; i++ {
+		pool = append(pool, self.new_rush_worker())
+	}
+
+	self.iterations_required = 0
+	best_score := -2147483647
+
+	for n := 0; n < iterations; n += SWAP_INTERVAL {
+
+		steps := hal.Min(SWAP_INTERVAL, iterations - n)
+
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		var failure interface{}
+
+		for i, w := range pool {
+
+			wg.Add(1)
+
+			go func(i int, w *rush_worker) {
+
+				defer func() {
+					if p := recover(); p != nil {		// Pass panics to the main goroutine, where they can be recovered.
+						mutex.Lock()
+						failure = p
+						mutex.Unlock()
+					}
+					wg.Done()
+				}()
+
+				for c := i; c < len(chains); c += workers {
+					self.evolve_chain(chains[c], w, n, steps, thresholds[c], real_enemy_ships, play_perfect)
+				}
+
+			}(i, w)
+		}
+
+		wg.Wait()
+
+		if failure != nil {
+			panic(failure)
+		}
+
+		if n == 0 {
+			self.null_score = chains[0].first_score		// Chain 0 started non-randomised, so this is the score of not moving.
+		}
+
+		score_0 := chains[0].genome.score
+
+		sort.SliceStable(chains, func(a, b int) bool {
+			return chains[a].genome.score > chains[b].genome.score
+		})
+
+		for c, chain := range chains {
+			self.genomes[c] = chain.genome
+		}
+
+		if chains[0].genome.score > score_0 {
+			self.cold_swaps++
+		}
+
+		if chains[0].genome.score > best_score {
+			self.iterations_required = n + steps - 1
+			best_score = chains[0].genome.score
+		}
+
+		if deterministic == false && self.game.Budget().Used(GA_BUDGET) {
+			self.game.Log("Emergency timeout in RunRushFightParallel() after %d iterations.", n + steps)
+			return
+		}
+	}
+}
+
+// evolve_chain runs iterations [first, first + steps) of a single chain, at a fixed heat.
+
+func (self *Evolver) evolve_chain(chain *rush_chain, w *rush_worker, first, steps int, threshold float64, real_enemy_ships []*hal.Ship, play_perfect bool) {
+
+	genome, backup := chain.genome, chain.backup
+
+	for n := first; n < first + steps; n++ {
+
+		backup.score = genome.score
+		for i := 0; i < len(genome.genes); i++ {
+			*backup.genes[i] = *genome.genes[i]
+		}
+
+		if n > 0 {
+			genome.MutateWith(chain.rng)
+		}
+
+		self.score_rush_genome(genome, w, real_enemy_ships, play_perfect)
+
+		if n == 0 {
+			chain.first_score = genome.score
+		}
+
+		if float64(genome.score) <= float64(backup.score) * threshold {
+			genome.score = backup.score
+			for i := 0; i < len(genome.genes); i++ {
+				*genome.genes[i] = *backup.genes[i]
+			}
+		}
+	}
+}
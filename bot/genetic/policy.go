@@ -0,0 +1,194 @@
+package genetic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	hal "../core"
+	rep "../replay"
+)
+
+// PolicyModel is an EnemyModel learnt from replays. A ship's situation is summarised as
+// how far away the nearest hostile ship is and whether it is outnumbered nearby; for each
+// situation we count which moves were made, as a speed and an angle relative to the
+// direction of that nearest hostile ship. Prediction is just the most common move.
+
+const (
+	POLICY_DIST_BIN = 7.0
+	POLICY_DIST_BINS = 8
+	POLICY_ANGLE_BIN = 30
+	POLICY_ANGLE_BINS = 360 / POLICY_ANGLE_BIN
+	POLICY_ODDS_RANGE = 15.0
+)
+
+type PolicyModel struct {
+	Counts			map[string][]int		`json:"counts"`		// Situation --> count of each move
+}
+
+func NewPolicyModel() *PolicyModel {
+	return &PolicyModel{Counts: make(map[string][]int)}
+}
+
+func LoadPolicyModel(filename string) (*PolicyModel, error) {
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := NewPolicyModel()
+
+	err = json.Unmarshal(b, ret)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	for key, counts := range ret.Counts {
+		if len(counts) != (hal.MAX_SPEED + 1) * POLICY_ANGLE_BINS {
+			return nil, fmt.Errorf("%s: bad counts for situation \"%s\"", filename, key)
+		}
+	}
+
+	return ret, nil
+}
+
+func (self *PolicyModel) Save(filename string) error {
+	b, err := json.Marshal(self)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, b, 0644)
+}
+
+func (self *PolicyModel) Name() string { return "policy" }
+
+func (self *PolicyModel) Predict(game *hal.Game, ship *hal.Ship) (float64, float64) {
+
+	my_ships := game.MyShips()
+
+	target := nearest_ship_of(my_ships, ship)
+	if target == nil {
+		return 0, 0
+	}
+
+	theirs, ours := 0, 0
+
+	for _, other := range game.ShipsOwnedBy(ship.Owner) {
+		if other.Dist(ship) < POLICY_ODDS_RANGE {
+			theirs++
+		}
+	}
+
+	for _, other := range my_ships {
+		if other.Dist(ship) < POLICY_ODDS_RANGE {
+			ours++
+		}
+	}
+
+	counts := self.Counts[policy_situation(ship.Dist(target), theirs, ours)]
+
+	best, best_count := 0, 0
+	for n, count := range counts {
+		if count > best_count {
+			best, best_count = n, count
+		}
+	}
+
+	if best_count == 0 {
+		return 0, 0
+	}
+
+	speed := best / POLICY_ANGLE_BINS
+	angle := ship.Angle(target) + (best % POLICY_ANGLE_BINS) * POLICY_ANGLE_BIN
+
+	return hal.Projection(0, 0, float64(speed), angle % 360)
+}
+
+// Learn adds the moves made by player pid's mobile ships in the replay. The "hostile" ships
+// are those of every other player.
+
+func (self *PolicyModel) Learn(replay *rep.Replay, pid int) {
+
+	for turn := 0; turn < len(replay.Moves) && turn < len(replay.Frames); turn++ {
+
+		frame := replay.Frames[turn]
+
+		thrusts := make(map[int]*rep.Move)
+		busy := make(map[int]bool)					// Ships that tried to dock or undock
+
+		for _, move := range replay.MovesBy(turn, pid) {
+			if move.Type == rep.THRUST {
+				thrusts[move.ShipId] = move
+			} else {
+				busy[move.ShipId] = true
+			}
+		}
+
+		for _, ship := range frame.ShipsOwnedBy(pid) {
+
+			if ship.DockedStatus != hal.UNDOCKED || busy[ship.Id] {
+				continue
+			}
+
+			var target *rep.Ship
+			best_dist := 999999.9
+			theirs, ours := 0, 0
+
+			for _, other := range frame.Ships {
+
+				d := hal.Dist(ship.X, ship.Y, other.X, other.Y)
+
+				if other.Owner == pid {
+					if d < POLICY_ODDS_RANGE {
+						theirs++
+					}
+					continue
+				}
+
+				if d < POLICY_ODDS_RANGE {
+					ours++
+				}
+
+				if d < best_dist {
+					target, best_dist = other, d
+				}
+			}
+
+			if target == nil {
+				continue
+			}
+
+			speed, angle_bin := 0, 0
+
+			if move := thrusts[ship.Id]; move != nil && move.Magnitude > 0 {
+				speed = hal.Min(hal.MAX_SPEED, move.Magnitude)
+				relative := (move.Angle % 360) - hal.Angle(ship.X, ship.Y, target.X, target.Y)
+				relative = ((relative % 360) + 360) % 360
+				angle_bin = ((relative + POLICY_ANGLE_BIN / 2) / POLICY_ANGLE_BIN) % POLICY_ANGLE_BINS
+			}
+
+			key := policy_situation(best_dist, theirs, ours)
+
+			if self.Counts[key] == nil {
+				self.Counts[key] = make([]int, (hal.MAX_SPEED + 1) * POLICY_ANGLE_BINS)
+			}
+
+			self.Counts[key][speed * POLICY_ANGLE_BINS + angle_bin]++
+		}
+	}
+}
+
+func policy_situation(dist float64, theirs, ours int) string {
+
+	dist_bin := hal.Min(int(dist / POLICY_DIST_BIN), POLICY_DIST_BINS - 1)
+
+	odds := "even"
+	if theirs > ours {
+		odds = "ahead"
+	} else if theirs < ours {
+		odds = "behind"
+	}
+
+	return fmt.Sprintf("%d/%s", dist_bin, odds)
+}
@@ -8,6 +8,11 @@ import (
 	pil "../pilot"
 )
 
+const (
+	GA_BUDGET = 1.0			// Fraction of the turn's Budget after which the GA settles for its best genome so far.
+							// All of it, as before budgets: on a GA turn nothing else runs afterwards.
+)
+
 var thresholds = [10]float64{1.0, 0.999, 0.995, 0.99, 0.98, 0.96, 0.93, 0.9, 0.8, 0.7}		// Metropolis Coupling score requirements
 
 var chase_permutations_3v3 = [][]int{
@@ -33,7 +38,18 @@ var chase_permutations_2v2 = [][]int{
 	[]int{1,0},
 }
 
-func EvolveRush(game *hal.Game, enemy_pid int, play_perfect bool) {
+// RushConfig holds the optional extras for EvolveRush(). The zero value gives the classic GA:
+// one turn of lookahead, the default enemy models, a single goroutine.
+
+type RushConfig struct {
+	Horizon					int
+	Tracker					*ModelTracker
+	Workers					int				// Goroutines for RunRushFightParallel(); 0 means use RunRushFight()
+	Seed					int64			// For RunRushFightParallel()
+	Deterministic			bool			// For RunRushFightParallel(): ignore the clock
+}
+
+func EvolveRush(game *hal.Game, enemy_pid int, play_perfect bool, config RushConfig) {
 
 	game.LogOnce("Entering EvolveRush() genetic algorithm!")
 
@@ -55,8 +71,19 @@ func EvolveRush(game *hal.Game, enemy_pid int, play_perfect bool) {
 
 	start_time := time.Now()
 
-	evolver := NewEvolver(game, my_mutable_ships, my_immutable_ships, enemy_ships, 10)
-	evolver.RunRushFight(15000, play_perfect)
+	evolver := NewEvolver(game, my_mutable_ships, my_immutable_ships, enemy_ships, 10, config.Horizon)
+
+	if config.Tracker != nil {
+		weights := config.Tracker.Weights()
+		evolver.SetEnemyModels(config.Tracker.Models(), weights)
+		game.Log("Enemy models: %s, weights %.2f", ModelNames(config.Tracker.Models()), weights)
+	}
+
+	if config.Workers > 0 {
+		evolver.RunRushFightParallel(game.Params().GAIterations, play_perfect, config.Workers, config.Seed, config.Deterministic)
+	} else {
+		evolver.RunRushFight(game.Params().GAIterations, play_perfect)
+	}
 
 	msg := pil.MSG_SECRET_SAUCE; if play_perfect { msg = pil.MSG_PERFECT_SAUCE }
 	evolver.ExecuteGenome(msg)
@@ -78,18 +105,7 @@ func EvolveRush(game *hal.Game, enemy_pid int, play_perfect bool) {
 
 func (self *Evolver) RunRushFight(iterations int, play_perfect bool) {
 
-	const (
-		PANIC_RANGE = 30		// How far the enemy can get before we worry
-	)
-
-	width, height := float64(self.game.Width()), float64(self.game.Height())
-	pid := self.game.Pid()
-
-	var real_enemy_ships []*hal.Ship
-	for i := self.first_enemy_index; i < len(self.sim.ships); i++ {
-		real_enemy_ship, _ := self.game.GetShip(self.sim.ships[i].id)
-		real_enemy_ships = append(real_enemy_ships, real_enemy_ship)
-	}
+	real_enemy_ships := self.real_enemy_ships()
 
 	self.iterations_required = 0
 	best_score := -2147483647
@@ -98,7 +114,13 @@ func (self *Evolver) RunRushFight(iterations int, play_perfect bool) {
 	// which is faster. Here's the storage space to do that with:
 
 	genome_backup := new(Genome)
-	genome_backup.Init(self.genome_length, false)
+	genome_backup.Init(self.genome_length * self.horizon, false)
+
+	w := &rush_worker{
+		sim: self.sim,
+		sim_without_enemies: self.sim_without_enemies,
+		stupid: make([]bool, self.genome_length),
+	}
 
 	for n := 0; n < iterations; n++ {
 
@@ -118,390 +140,479 @@ func (self *Evolver) RunRushFight(iterations int, play_perfect bool) {
 				genome.Mutate()
 			}
 
-			genome.score = 0
+			self.score_rush_genome(genome, w, real_enemy_ships, play_perfect)
 
-			// We run some different scenarios of what the enemy will do.
+			if n == 0 && c == 0 {
+				self.null_score = genome.score		// Record the score of not moving. Relies on no mutation in n0 and non-randomised c0.
+			}
 
-			for scenario := 0; scenario < 3; scenario++ {
+			if float64(genome.score) <= float64(genome_backup.score) * thresholds[c] {
 
-				var sim *Sim
+				// Reset the genome to how it was.
 
-				if scenario == 0 {						// Scenario 0 is the enemy ships not existing at at all (so we don't hit planets, etc)
-					sim = self.sim_without_enemies
-				} else {
-					sim = self.sim
+				genome.score = genome_backup.score
+				for i := 0; i < len(genome.genes); i++ {
+					*genome.genes[i] = *genome_backup.genes[i]
 				}
+			}
+		}
 
-				sim.Reset()								// We used to make a copy of the sim, but that was slower. Now just reset every time.
+		score_0 := self.genomes[0].score
 
-				my_mutable_simships := sim.ships[0:self.genome_length]
+		sort.SliceStable(self.genomes, func(a, b int) bool {
+			return self.genomes[a].score > self.genomes[b].score		// Note the reversed sort, high scores come first.
+		})
 
-				for i := 0; i < len(genome.genes); i++ {
+		if self.genomes[0].score > score_0 {
+			self.cold_swaps++
+		}
+
+		if self.genomes[0].score > best_score {
+			self.iterations_required = n								// info only.
+			best_score = self.genomes[0].score
+		}
+
+		if self.game.Budget().Used(GA_BUDGET) {
+			self.game.Log("Emergency timeout in RunRushFight() after %d iterations.", n)
+			return
+		}
+	}
+}
+
+func (self *Evolver) real_enemy_ships() []*hal.Ship {
+	var ret []*hal.Ship
+	for i := self.first_enemy_index; i < len(self.sim.ships); i++ {
+		real_enemy_ship, _ := self.game.GetShip(self.sim.ships[i].id)
+		ret = append(ret, real_enemy_ship)
+	}
+	return ret
+}
 
-					if sim.ships[i].dockedstatus == hal.UNDOCKED {		// This really should be true
+// A rush_worker holds what a goroutine needs of its own to score genomes.
 
-						speed := genome.genes[i].speed
-						angle := genome.genes[i].angle
+type rush_worker struct {
+	sim						*Sim
+	sim_without_enemies		*Sim
+	stupid					[]bool			// Which of our ships did something stupid in any scenario
+}
+
+func (self *Evolver) new_rush_worker() *rush_worker {
+	return &rush_worker{
+		sim: self.sim.Copy(),
+		sim_without_enemies: self.sim_without_enemies.Copy(),
+		stupid: make([]bool, self.genome_length),
+	}
+}
 
-						vel_x, vel_y := hal.Projection(0, 0, float64(speed), angle)
+// score_rush_genome runs the genome through every scenario and sets its score.
 
-						sim.ships[i].vel_x = vel_x						// Relying on our mutable
-						sim.ships[i].vel_y = vel_y						// ships being stored first.
+func (self *Evolver) score_rush_genome(genome *Genome, w *rush_worker, real_enemy_ships []*hal.Ship, play_perfect bool) {
 
-					} else {
-						panic("RunRushFight(): got docked ship where mutable ship should be")
-					}
-				}
+	panic_range := self.game.Params().PanicRange		// How far the enemy can get before we worry
 
-				for i := len(genome.genes); i < len(sim.ships); i++ {
+	width, height := float64(self.game.Width()), float64(self.game.Height())
+	pid := self.game.Pid()
 
-					if sim.ships[i].dockedstatus == hal.UNDOCKED {
+	genome.score = 0
 
-						if sim.ships[i].owner != pid {
+	for i := range w.stupid {
+		w.stupid[i] = false
+	}
 
-							switch scenario {
+	// We run some different scenarios of what the enemy will do: scenario 0 has no enemies at
+	// all, the rest have the enemy doing what each of our EnemyModels predicts.
 
-							case 0:
-								// Scenario 0 is the enemy ships not existing at at all (so we don't hit planets, etc)
-								panic("RunRushFight(): got enemy ship in scenario 0")
+	for scenario := 0; scenario <= len(self.models); scenario++ {
 
-							case 1:
-								sim.ships[i].vel_x = sim.ships[i].real_ship.Dx
-								sim.ships[i].vel_y = sim.ships[i].real_ship.Dy
+		var sim *Sim
 
-							case 2:
-								// Scenario 2 is the enemy ships making no move
+		if scenario == 0 {						// Scenario 0 is the enemy ships not existing at at all (so we don't hit planets, etc)
+			sim = w.sim_without_enemies
+		} else {
+			sim = w.sim
+		}
 
-							}
-						}
-					}
+		sim.Reset()								// We used to make a copy of the sim, but that was slower. Now just reset every time.
+
+		my_mutable_simships := sim.ships[0:self.genome_length]
+
+		// With a horizon of more than 1 turn, the genome holds each turn's orders in turn, and the
+		// sim is stepped through all of them. We mostly score the end state, but the thirteens
+		// are only meaningful for the first turn, and crashing on the way counts as crashing.
+
+		for t := 0; t < self.horizon; t++ {
+
+			if t > 0 {
+				sim.NextTurn()
+			}
+
+			genes := genome.genes[t * self.genome_length : (t + 1) * self.genome_length]
+
+			for i := 0; i < self.genome_length; i++ {
+
+				if sim.ships[i].hp <= 0 {								// Died on an earlier turn
+					continue
 				}
 
-				sim.Step()
+				if sim.ships[i].dockedstatus == hal.UNDOCKED {		// This really should be true
 
-				// SCORING -----------------------------------------------------------------------------------------------------------
+					speed := genes[i].speed
+					angle := genes[i].angle
 
-				// Damage...
+					vel_x, vel_y := hal.Projection(0, 0, float64(speed), angle)
 
-				for _, ship := range sim.ships {
-					if ship.hp > 0 {
-						if ship.owner != pid {
-							genome.score -= ship.hp * 100
-						} else {
-							genome.score += ship.hp * 100
-						}
-					}
+					sim.ships[i].vel_x = vel_x						// Relying on our mutable
+					sim.ships[i].vel_y = vel_y						// ships being stored first.
+
+				} else {
+					panic("RunRushFight(): got docked ship where mutable ship should be")
 				}
+			}
 
-				// Other scores only need to be run in one scenario to work...
+			for i := self.genome_length; i < len(sim.ships); i++ {
 
-				if scenario == 2 {
+				if sim.ships[i].dockedstatus == hal.UNDOCKED && sim.ships[i].hp > 0 {
 
-					// A good scenario to run our stupidity checks in. In particular, enemy docked ships exist
-					// in this scenario and our ships are flagged as stupid if they have collided with them.
+					if sim.ships[i].owner != pid {
 
-					for _, ship := range my_mutable_simships {
-						if ship.stupid_death || ship.x <= 0 || ship.x >= width || ship.y <= 0 || ship.y >= height {
-							genome.score -= 9999999
+						if scenario == 0 {
+							// Scenario 0 is the enemy ships not existing at at all (so we don't hit planets, etc)
+							panic("RunRushFight(): got enemy ship in scenario 0")
 						}
+
+						vel := self.model_vels[scenario - 1][i]
+
+						sim.ships[i].vel_x = vel.x
+						sim.ships[i].vel_y = vel.y
 					}
 				}
+			}
+
+			sim.Step()
 
-				if scenario == 0 {
+			if scenario == 0 && t == 0 {
+				genome.score += self.score_thirteens(my_mutable_simships, real_enemy_ships, play_perfect)
+			}
 
-					// A good scenario to run every other check in.
-					// Note that enemy_sim_ship_ptrs is empty here, so use real ships...
+			if t < self.horizon - 1 {
+				for _, ship := range my_mutable_simships {
+					if ship.x <= 0 || ship.x >= width || ship.y <= 0 || ship.y >= height {
+						ship.stupid_death = true			// Penalised below.
+					}
+				}
+			}
+		}
 
-					// EDGES OF SPACE / PLANET AVOIDANCE -----------------------------------------------------------------------------
+		// SCORING -----------------------------------------------------------------------------------------------------------
 
-					for _, ship := range my_mutable_simships {
+		// Damage, weighted by how much we believe in the scenario...
 
-						// Modest penalty for getting near edge of space...
+		damage_score := 0
 
-						horiz_clearance := hal.MinFloat(ship.x, width - ship.x)
-						vert_clearance := hal.MinFloat(ship.y, height - ship.y)
+		for _, ship := range sim.ships {
+			if ship.hp > 0 {
+				if ship.owner != pid {
+					damage_score -= ship.hp * 100
+				} else {
+					damage_score += ship.hp * 100
+				}
+			}
+		}
 
-						if horiz_clearance < 12.5 {
-							genome.score -= int(1000.0 - horiz_clearance * 20)		// Needs to be able to override get-close-to-ship reward.
-						}
-						if vert_clearance < 12.5 {
-							genome.score -= int(1000.0 - vert_clearance * 20)
-						}
+		if scenario == 0 {
+			genome.score += damage_score
+		} else {
+			genome.score += int(float64(damage_score) * self.weights[scenario - 1])
+		}
 
-						// Getting really near planets is like death...
+		// Other scores only need to be run in one scenario to work...
 
-						for _, planet := range sim.planets {
+		if scenario > 0 && self.punish_stupidity[scenario - 1] {
 
-							clearance := hal.Dist(ship.x, ship.y, planet.x, planet.y) - (planet.radius + 0.5)
+			// Stupidity checks need the enemy present. In particular, enemy docked ships exist in these
+			// scenarios and our ships are flagged as stupid if they have collided with them. A ship is
+			// only punished once, however many scenarios it was stupid in.
 
-							if clearance < 0.5 {
-								genome.score -= (500000 - int(clearance * 20))		// Amusing subtraction but should be effective.
-							}
-						}
-					}
+			for i, ship := range my_mutable_simships {
+				if ship.stupid_death || ship.x <= 0 || ship.x >= width || ship.y <= 0 || ship.y >= height {
+					w.stupid[i] = true
+				}
+			}
+		}
 
-					// DISTANCE ------------------------------------------------------------------------------------------------------
+		if scenario == 0 {
 
-					// Keep close to enemy. Deal with split enemies. The important cases are 3v3, 3v2, and 2v2.
-					// I tried writing general stuff but it was simpler just to handle the individual cases.
+			// A good scenario to run every other check in.
+			// Note that enemy_sim_ship_ptrs is empty here, so use real ships...
 
-					// Note: since any particular move can only reduce the bad score by ~63000, it's not actually enough to override
-					// the thirteens code below. In practice, this doesn't seem to be an issue in chasing splits correctly, I think
-					// because it's usually the case that one ship can't get a thirteen and so goes hunting for the rogue ship instead?
+			// EDGES OF SPACE / PLANET AVOIDANCE -----------------------------------------------------------------------------
 
-					if len(genome.genes) == 3 && len(real_enemy_ships) >= 2 {
+			for _, ship := range my_mutable_simships {
 
-						// Handles both 3v2 and 3v3.
+				// Modest penalty for getting near edge of space...
 
-						dist0 := 999999.9
-						dist1 := 999999.9
-						dist2 := 999999.9
+				horiz_clearance := hal.MinFloat(ship.x, width - ship.x)
+				vert_clearance := hal.MinFloat(ship.y, height - ship.y)
 
-						permutations := chase_permutations_3v2;
-						if len(real_enemy_ships) > 2 {
-							permutations = chase_permutations_3v3;
-						}
+				if horiz_clearance < 12.5 {
+					genome.score -= int(1000.0 - horiz_clearance * 20)		// Needs to be able to override get-close-to-ship reward.
+				}
+				if vert_clearance < 12.5 {
+					genome.score -= int(1000.0 - vert_clearance * 20)
+				}
 
-						for _, perm := range permutations {
+				// Getting really near planets is like death...
 
-							this_dist0 := sim.ships[0].Dist(real_enemy_ships[perm[0]])
-							this_dist1 := sim.ships[1].Dist(real_enemy_ships[perm[1]])
-							this_dist2 := sim.ships[2].Dist(real_enemy_ships[perm[2]])
+				for _, planet := range sim.planets {
 
-							if  (this_dist0 + this_dist1 + this_dist2)   <   (dist0 + dist1 + dist2)  {
+					clearance := hal.Dist(ship.x, ship.y, planet.x, planet.y) - (planet.radius + 0.5)
 
-								dist0, dist1, dist2 = this_dist0, this_dist1, this_dist2
+					if clearance < 0.5 {
+						genome.score -= (500000 - int(clearance * 20))		// Amusing subtraction but should be effective.
+					}
+				}
+			}
 
-							}
-						}
+			// DISTANCE ------------------------------------------------------------------------------------------------------
 
-						if dist0 < PANIC_RANGE {
-							genome.score -= int(dist0 * 9)
-						} else {
-							genome.score -= int(dist0 * 9000)
-						}
+			// Keep close to enemy. Deal with split enemies. The important cases are 3v3, 3v2, and 2v2.
+			// I tried writing general stuff but it was simpler just to handle the individual cases.
 
-						if dist1 < PANIC_RANGE {
-							genome.score -= int(dist1 * 9)
-						} else {
-							genome.score -= int(dist1 * 9000)
-						}
+			// Note: since any particular move can only reduce the bad score by ~63000, it's not actually enough to override
+			// the thirteens code below. In practice, this doesn't seem to be an issue in chasing splits correctly, I think
+			// because it's usually the case that one ship can't get a thirteen and so goes hunting for the rogue ship instead?
 
-						if dist2 < PANIC_RANGE {
-							genome.score -= int(dist2 * 9)
-						} else {
-							genome.score -= int(dist2 * 9000)
-						}
+			if self.genome_length == 3 && len(real_enemy_ships) >= 2 {
 
-					} else if len(genome.genes) == 2 && len(real_enemy_ships) == 2 {
+				// Handles both 3v2 and 3v3.
 
-						dist0 := 999999.9
-						dist1 := 999999.9
+				dist0 := 999999.9
+				dist1 := 999999.9
+				dist2 := 999999.9
 
-						for _, perm := range chase_permutations_2v2 {
+				permutations := chase_permutations_3v2;
+				if len(real_enemy_ships) > 2 {
+					permutations = chase_permutations_3v3;
+				}
 
-							this_dist0 := sim.ships[0].Dist(real_enemy_ships[perm[0]])
-							this_dist1 := sim.ships[1].Dist(real_enemy_ships[perm[1]])
+				for _, perm := range permutations {
 
-							if  (this_dist0 + this_dist1)   <   (dist0 + dist1)  {
+					this_dist0 := sim.ships[0].Dist(real_enemy_ships[perm[0]])
+					this_dist1 := sim.ships[1].Dist(real_enemy_ships[perm[1]])
+					this_dist2 := sim.ships[2].Dist(real_enemy_ships[perm[2]])
 
-								dist0, dist1 = this_dist0, this_dist1
+					if  (this_dist0 + this_dist1 + this_dist2)   <   (dist0 + dist1 + dist2)  {
 
-							}
-						}
+						dist0, dist1, dist2 = this_dist0, this_dist1, this_dist2
 
-						if dist0 < PANIC_RANGE {
-							genome.score -= int(dist0 * 9)
-						} else {
-							genome.score -= int(dist0 * 9000)
-						}
+					}
+				}
 
-						if dist1 < PANIC_RANGE {
-							genome.score -= int(dist1 * 9)
-						} else {
-							genome.score -= int(dist1 * 9000)
-						}
+				if dist0 < panic_range {
+					genome.score -= int(dist0 * 9)
+				} else {
+					genome.score -= int(dist0 * 9000)
+				}
 
-					} else {
+				if dist1 < panic_range {
+					genome.score -= int(dist1 * 9)
+				} else {
+					genome.score -= int(dist1 * 9000)
+				}
+
+				if dist2 < panic_range {
+					genome.score -= int(dist2 * 9)
+				} else {
+					genome.score -= int(dist2 * 9000)
+				}
 
-						// Minimise the biggest distances in other cases...
+			} else if self.genome_length == 2 && len(real_enemy_ships) == 2 {
 
-						// Use a small, overridable score, unless the distance is > 40
-						// in which case use a massive all-encompassing score.
+				dist0 := 999999.9
+				dist1 := 999999.9
 
-						highest_enemy_clearance := -1.0
+				for _, perm := range chase_permutations_2v2 {
 
-						for _, enemy := range real_enemy_ships {
+					this_dist0 := sim.ships[0].Dist(real_enemy_ships[perm[0]])
+					this_dist1 := sim.ships[1].Dist(real_enemy_ships[perm[1]])
 
-							closest_range := 999999.9
+					if  (this_dist0 + this_dist1)   <   (dist0 + dist1)  {
 
-							for _, ship := range my_mutable_simships {
-								d := ship.Dist(enemy)
-								if d < closest_range {
-									closest_range = d
-								}
-							}
+						dist0, dist1 = this_dist0, this_dist1
 
-							if closest_range > highest_enemy_clearance {
-								highest_enemy_clearance = closest_range
-							}
-						}
+					}
+				}
 
-						highest_friendly_clearance := -1.0
+				if dist0 < panic_range {
+					genome.score -= int(dist0 * 9)
+				} else {
+					genome.score -= int(dist0 * 9000)
+				}
 
-						for _, ship := range my_mutable_simships {
+				if dist1 < panic_range {
+					genome.score -= int(dist1 * 9)
+				} else {
+					genome.score -= int(dist1 * 9000)
+				}
 
-							closest_range := 999999.9
+			} else {
 
-							for _, enemy := range real_enemy_ships {
-								d := ship.Dist(enemy)
-								if d < closest_range {
-									closest_range = d
-								}
-							}
+				// Minimise the biggest distances in other cases...
 
-							if closest_range > highest_friendly_clearance {
-								highest_friendly_clearance = closest_range
-							}
+				// Use a small, overridable score, unless the distance is > 40
+				// in which case use a massive all-encompassing score.
 
-							// While we're at it, make sure the ship wants to move nearer to some enemy.
-							// Otherwise, it might stand still if it's not affecting the clearances.
+				highest_enemy_clearance := -1.0
 
-							genome.score -= int(closest_range * 2)
-						}
+				for _, enemy := range real_enemy_ships {
 
-						if highest_enemy_clearance < 40 {
-							genome.score -= int(highest_enemy_clearance * 9)		// Use different numbers such that this can override...
-						} else {
-							genome.score -= int(highest_enemy_clearance * 9000)
-						}
+					closest_range := 999999.9
 
-						if highest_friendly_clearance < 40 {
-							genome.score -= int(highest_friendly_clearance * 6)		// ...the desire to approach the nearest enemy if need be.
-						} else {
-							genome.score -= int(highest_friendly_clearance * 6000)
+					for _, ship := range my_mutable_simships {
+						d := ship.Dist(enemy)
+						if d < closest_range {
+							closest_range = d
 						}
 					}
 
-					// PERFECT THIRTEEN RANGE TRICK ----------------------------------------------------------------------------------
+					if closest_range > highest_enemy_clearance {
+						highest_enemy_clearance = closest_range
+					}
+				}
 
-					var good_thirteens = make(map[int][]*SimShip)						// Enemy ship ID --> my ships hitting it
+				highest_friendly_clearance := -1.0
 
-					if play_perfect {
+				for _, ship := range my_mutable_simships {
 
-						for _, ship := range my_mutable_simships {
+					closest_range := 999999.9
 
-							// In "perfect" mode we give huge bonuses to moves that can only ever be hit by 1 enemy;
-							// which means being < 13 away from the *starting* location of 1 enemy.
+					for _, enemy := range real_enemy_ships {
+						d := ship.Dist(enemy)
+						if d < closest_range {
+							closest_range = d
+						}
+					}
 
-							var thirteens	[]int										// IDs of ships that might be able to hit us.
-							var twelves		[]int										// As above, but with some tolerance.
-							var eights		[]int										// IDs of ships that might be able to ram us.
+					if closest_range > highest_friendly_clearance {
+						highest_friendly_clearance = closest_range
+					}
 
-							for _, enemy_ship := range real_enemy_ships {				// Must use real_enemy_ships, since sim enemies aren't present.
+					// While we're at it, make sure the ship wants to move nearer to some enemy.
+					// Otherwise, it might stand still if it's not affecting the clearances.
 
-								if enemy_ship.Doomed {
-									continue				// No need to worry about getting to the right distance away from doomed ships.
-								}
+					genome.score -= int(closest_range * 2)
+				}
 
-								if ship.Dist(enemy_ship) < 13 {
-									thirteens = append(thirteens, enemy_ship.Id)
-								}
-								if ship.Dist(enemy_ship) < 12 {
-									twelves = append(twelves, enemy_ship.Id)
-								}
-								if ship.Dist(enemy_ship) < 8 {
-									eights = append(eights, enemy_ship.Id)
-								}
-							}
+				if highest_enemy_clearance < 40 {
+					genome.score -= int(highest_enemy_clearance * 9)		// Use different numbers such that this can override...
+				} else {
+					genome.score -= int(highest_enemy_clearance * 9000)
+				}
 
-							if len(thirteens) == 1 && ship.fires_at_time_0 == false {
-								genome.score += 100000
-								enemy_ship_id := thirteens[0]
-								good_thirteens[enemy_ship_id] = append(good_thirteens[enemy_ship_id], ship)
-							}
+				if highest_friendly_clearance < 40 {
+					genome.score -= int(highest_friendly_clearance * 6)		// ...the desire to approach the nearest enemy if need be.
+				} else {
+					genome.score -= int(highest_friendly_clearance * 6000)
+				}
+			}
+		}
 
-							ideal_thirteens := 1
-							if ship.fires_at_time_0 {		// If we're already committed to shooting (because a target's in range
-								ideal_thirteens = 0			// already) then we should just back away from everything if we can.
-							}
+	}
 
-							if len(thirteens) > ideal_thirteens {
-								genome.score -= 100000 * (len(thirteens) - ideal_thirteens)
-							}
+	for i := range w.stupid {
+		if w.stupid[i] {
+			genome.score -= 9999999
+		}
+	}
+}
 
-							if len(twelves) > 1 {			// We have this in case we just can't find a way to avoid > 2 thirteens.
-								genome.score -= 200000		// In which case we need to punish it more if it goes even worse.
-							}
+// score_thirteens is the "perfect" range trick. It judges where our ships are after the first turn
+// (whatever the horizon), relative to where the enemy ships start it.
 
-							if len(eights) > 0 {			// Note > 0. This stops us getting accidentally rammed when enemy ship is solo.
-								genome.score -= 300000
-							}
-						}
-					}
+func (self *Evolver) score_thirteens(my_mutable_simships []*SimShip, real_enemy_ships []*hal.Ship, play_perfect bool) int {
 
-					for _, hitters := range good_thirteens {
+	score := 0
 
-						genome.score += (len(hitters) - 1) * 15000		// Modest bonus for coordinated thirteens (should be enough)
+	var good_thirteens = make(map[int][]*SimShip)						// Enemy ship ID --> my ships hitting it
 
-						if len(hitters) == 2 {
+	if play_perfect {
 
-							d := hal.Dist(hitters[0].x, hitters[0].y, hitters[1].x, hitters[1].y)
+		for _, ship := range my_mutable_simships {
 
-							if d > 3 {
-								genome.score -= int(d - 2)				// Tiniest penalty for hitters being far apart
-							}
+			// In "perfect" mode we give huge bonuses to moves that can only ever be hit by 1 enemy;
+			// which means being < 13 away from the *starting* location of 1 enemy.
 
-						} else if len(hitters) == 3 {
+			var thirteens	[]int										// IDs of ships that might be able to hit us.
+			var twelves		[]int										// As above, but with some tolerance.
+			var eights		[]int										// IDs of ships that might be able to ram us.
 
-							d1 := hal.Dist(hitters[0].x, hitters[0].y, hitters[1].x, hitters[1].y)
-							d2 := hal.Dist(hitters[0].x, hitters[0].y, hitters[2].x, hitters[2].y)
-							d3 := hal.Dist(hitters[1].x, hitters[1].y, hitters[2].x, hitters[2].y)
+			for _, enemy_ship := range real_enemy_ships {				// Must use real_enemy_ships, since sim enemies aren't present.
 
-							d := hal.MaxFloatVariadic(d1, d2, d3)
+				if enemy_ship.Doomed {
+					continue				// No need to worry about getting to the right distance away from doomed ships.
+				}
 
-							if d > 4 {
-								genome.score -= int(d - 3)				// Tiniest penalty for hitters being far apart
-							}
-						}
-					}
+				if ship.Dist(enemy_ship) < 13 {
+					thirteens = append(thirteens, enemy_ship.Id)
+				}
+				if ship.Dist(enemy_ship) < 12 {
+					twelves = append(twelves, enemy_ship.Id)
+				}
+				if ship.Dist(enemy_ship) < 8 {
+					eights = append(eights, enemy_ship.Id)
 				}
+			}
 
+			if len(thirteens) == 1 && ship.fires_at_time_0 == false {
+				score += 100000
+				enemy_ship_id := thirteens[0]
+				good_thirteens[enemy_ship_id] = append(good_thirteens[enemy_ship_id], ship)
 			}
 
-			if n == 0 && c == 0 {
-				self.null_score = genome.score		// Record the score of not moving. Relies on no mutation in n0 and non-randomised c0.
+			ideal_thirteens := 1
+			if ship.fires_at_time_0 {		// If we're already committed to shooting (because a target's in range
+				ideal_thirteens = 0			// already) then we should just back away from everything if we can.
 			}
 
-			if float64(genome.score) <= float64(genome_backup.score) * thresholds[c] {
+			if len(thirteens) > ideal_thirteens {
+				score -= 100000 * (len(thirteens) - ideal_thirteens)
+			}
 
-				// Reset the genome to how it was.
+			if len(twelves) > 1 {			// We have this in case we just can't find a way to avoid > 2 thirteens.
+				score -= 200000		// In which case we need to punish it more if it goes even worse.
+			}
 
-				genome.score = genome_backup.score
-				for i := 0; i < len(genome.genes); i++ {
-					*genome.genes[i] = *genome_backup.genes[i]
-				}
+			if len(eights) > 0 {			// Note > 0. This stops us getting accidentally rammed when enemy ship is solo.
+				score -= 300000
 			}
 		}
+	}
 
-		score_0 := self.genomes[0].score
+	for _, hitters := range good_thirteens {
 
-		sort.SliceStable(self.genomes, func(a, b int) bool {
-			return self.genomes[a].score > self.genomes[b].score		// Note the reversed sort, high scores come first.
-		})
+		score += (len(hitters) - 1) * 15000		// Modest bonus for coordinated thirteens (should be enough)
 
-		if self.genomes[0].score > score_0 {
-			self.cold_swaps++
-		}
+		if len(hitters) == 2 {
 
-		if self.genomes[0].score > best_score {
-			self.iterations_required = n								// info only.
-			best_score = self.genomes[0].score
-		}
+			d := hal.Dist(hitters[0].x, hitters[0].y, hitters[1].x, hitters[1].y)
 
-		if time.Now().Sub(self.game.ParseTime()) > 1500 * time.Millisecond {
-			self.game.Log("Emergency timeout in RunRushFight() after %d iterations.", n)
-			return
+			if d > 3 {
+				score -= int(d - 2)				// Tiniest penalty for hitters being far apart
+			}
+
+		} else if len(hitters) == 3 {
+
+			d1 := hal.Dist(hitters[0].x, hitters[0].y, hitters[1].x, hitters[1].y)
+			d2 := hal.Dist(hitters[0].x, hitters[0].y, hitters[2].x, hitters[2].y)
+			d3 := hal.Dist(hitters[1].x, hitters[1].y, hitters[2].x, hitters[2].y)
+
+			d := hal.MaxFloatVariadic(d1, d2, d3)
+
+			if d > 4 {
+				score -= int(d - 3)				// Tiniest penalty for hitters being far apart
+			}
 		}
 	}
-}
+
+	return score
+}
\ No newline at end of file
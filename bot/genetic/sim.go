@@ -66,6 +66,20 @@ func (self *Sim) Reset() {
 	}
 }
 
+// NextTurn readies the sim for another Step(), when looking more than 1 turn ahead.
+// Positions, HP and deaths carry over; velocities are cleared and weapons can fire again.
+
+func (self *Sim) NextTurn() {
+	for _, ship := range self.ships {
+		ship.vel_x = 0
+		ship.vel_y = 0
+		if ship.ship_state == ALIVE {
+			ship.weapon_state = READY
+		}
+		ship.actual_targets = nil
+	}
+}
+
 type SimEntity struct {
 	x				float64
 	y				float64
@@ -76,6 +90,16 @@ type SimEntity struct {
 
 type SimPlanet struct {
 	SimEntity
+
+	// The rest is only used by FullSim...
+
+	id					int
+	hp					int
+	owner				int
+	docking_spots		int
+	current_production	int
+	docked				[]int				// Ship IDs
+	exploded			bool
 }
 
 type SimShip struct {
@@ -89,7 +113,13 @@ type SimShip struct {
 	actual_targets	[]*SimShip			// Who we actually, really, definitely shoot at.
 	stupid_death	bool				// Crude hack (v73) since we want to avoid this at all costs.
 	fires_at_time_0	bool				// Whether the real ship cannot fire again and so we shouldn't go for "perfect" range.
-	real_ship		*hal.Ship
+	real_ship		*hal.Ship			// nil for ships spawned by a FullSim.
+
+	// The rest is only used by FullSim...
+
+	docked_planet		int
+	docking_progress	int
+	pending_damage		int
 }
 
 func (self *SimShip) Dist(e hal.Entity) float64 {
@@ -167,6 +197,9 @@ func (self *Sim) Step() {
 
 	for _, planet := range self.planets {
 		for _, ship := range self.ships {
+			if ship.hp <= 0 {
+				continue
+			}
 			t, ok := CollisionTime(planet.radius + 0.5, &ship.SimEntity, &planet.SimEntity)
 			if ok && t >= 0 && t <= 1 {
 				possible_events = append(possible_events, &PossibleEvent{ship, nil, planet, t, PLANET_COLLISION})
@@ -299,7 +332,7 @@ func SetupSim(game *hal.Game, relevant_ships []*hal.Ship) *Sim {
 			if planet.Dist(ship) < planet.Radius + 8.5 {		// Only include relevant planets. Some fudge so we can see them at distance.
 
 				sim.planets = append(sim.planets, &SimPlanet{
-					SimEntity{
+					SimEntity: SimEntity{
 						x: planet.X,
 						y: planet.Y,
 						radius: planet.Radius,
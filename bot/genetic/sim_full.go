@@ -0,0 +1,630 @@
+package genetic
+
+import (
+	"sort"
+
+	hal "../core"
+)
+
+// FullSim is a Sim with the rest of the game rules: production and spawning, docking and
+// undocking, planet HP and explosions, and the edges of the map. Unlike the rush sim, which
+// only ever looks one turn ahead at a handful of ships, it holds the whole game and can be
+// stepped for as many turns as wanted.
+//
+// The state is seeded from a core.Game, whose ships have already had their docking status
+// pushed forward a turn (see fudge_dock_status), so Step() does docking last, not first.
+// Production also comes after movement, and new ships need a clear spot given where
+// everything ended up. Checked against the reference replays this gets nearly everything
+// right; the misses are mostly spawned ships getting each other's IDs. (The cmd/simcheck tool
+// does this check.)
+//
+// The rules themselves -- production, spawning, contested docks, explosions -- are shared with
+// the engine, see core/rules.go.
+
+type FullSim struct {
+	Sim
+	width			float64
+	height			float64
+	turn			int
+	next_sid		int
+	orders			map[int]hal.Order		// Ship ID --> order for the next Step()
+}
+
+func NewFullSim(game *hal.Game) *FullSim {
+
+	ret := &FullSim{
+		width: float64(game.Width()),
+		height: float64(game.Height()),
+		turn: game.Turn(),
+		orders: make(map[int]hal.Order),
+	}
+
+	for _, planet := range game.AllPlanets() {
+
+		sim_planet := &SimPlanet{
+			SimEntity: SimEntity{
+				x: planet.X,
+				y: planet.Y,
+				radius: planet.Radius,
+			},
+			id: planet.Id,
+			hp: planet.HP,
+			owner: planet.Owner,
+			docking_spots: planet.DockingSpots,
+			current_production: planet.CurrentProduction,
+		}
+
+		for _, ship := range game.ShipsDockedAt(planet) {
+			sim_planet.docked = append(sim_planet.docked, ship.Id)
+		}
+
+		ret.planets = append(ret.planets, sim_planet)
+	}
+
+	for _, ship := range game.AllShips() {
+
+		ret.ships = append(ret.ships, &SimShip{
+			SimEntity: SimEntity{
+				x: ship.X,
+				y: ship.Y,
+				radius: hal.SHIP_RADIUS,
+			},
+			ship_state: ALIVE,
+			weapon_state: READY,
+			dockedstatus: ship.DockedStatus,
+			docked_planet: ship.DockedPlanet,
+			docking_progress: ship.DockingProgress,
+			owner: ship.Owner,
+			hp: ship.HP,
+			id: ship.Id,
+			real_ship: ship,
+		})
+
+		if ship.Id >= ret.next_sid {
+			ret.next_sid = ship.Id + 1			// Only a guess, since IDs are shared by all players.
+		}
+	}
+
+	return ret
+}
+
+func (self *FullSim) Copy() *FullSim {
+
+	ret := &FullSim{
+		width: self.width,
+		height: self.height,
+		turn: self.turn,
+		next_sid: self.next_sid,
+		orders: make(map[int]hal.Order),
+	}
+
+	for _, planet := range self.planets {
+		new_planet := new(SimPlanet)
+		*new_planet = *planet
+		new_planet.docked = append([]int(nil), planet.docked...)
+		ret.planets = append(ret.planets, new_planet)
+	}
+
+	for _, ship := range self.ships {
+		new_ship := new(SimShip)
+		*new_ship = *ship
+		new_ship.actual_targets = nil
+		ret.ships = append(ret.ships, new_ship)
+	}
+
+	for sid, order := range self.orders {
+		ret.orders[sid] = order
+	}
+
+	return ret
+}
+
+func (self *FullSim) Turn() int { return self.turn }
+func (self *FullSim) Ships() []*SimShip { return self.ships }
+func (self *FullSim) Planets() []*SimPlanet { return self.planets }
+
+func (self *FullSim) GetShip(sid int) (*SimShip, bool) {
+	for _, ship := range self.ships {
+		if ship.id == sid {
+			return ship, true
+		}
+	}
+	return nil, false
+}
+
+func (self *FullSim) GetPlanet(plid int) (*SimPlanet, bool) {
+	for _, planet := range self.planets {
+		if planet.id == plid {
+			return planet, true
+		}
+	}
+	return nil, false
+}
+
+func (self *FullSim) ShipsOwnedBy(pid int) []*SimShip {
+	var ret []*SimShip
+	for _, ship := range self.ships {
+		if ship.owner == pid {
+			ret = append(ret, ship)
+		}
+	}
+	return ret
+}
+
+func (self *FullSim) TotalHP(pid int) int {
+	ret := 0
+	for _, ship := range self.ships {
+		if ship.owner == pid {
+			ret += ship.hp
+		}
+	}
+	return ret
+}
+
+// Orders are stored (as core.Orders, like the Game's) and only checked for legality during
+// Step(), as the real engine does.
+
+func (self *FullSim) Thrust(sid, speed, angle int) {
+	self.orders[sid] = hal.ThrustOrder(sid, speed, angle)
+}
+
+func (self *FullSim) Dock(sid, plid int) {
+	self.orders[sid] = hal.DockOrder(sid, plid)
+}
+
+func (self *FullSim) Undock(sid int) {
+	self.orders[sid] = hal.UndockOrder(sid)
+}
+
+func (self *FullSim) ClearOrders() {
+	self.orders = make(map[int]hal.Order)
+}
+
+// ---------------------------------------
+
+func (self *FullSim) Step() {
+
+	self.apply_orders()
+	self.process_movement()
+	self.process_production()
+	self.process_docking()
+
+	self.orders = make(map[int]hal.Order)
+	self.turn++
+}
+
+func (self *FullSim) process_production() {
+
+	for _, planet := range self.planets {
+
+		if planet.owner == -1 {
+			continue
+		}
+
+		docked := 0
+
+		for _, sid := range planet.docked {
+			ship, ok := self.GetShip(sid)
+			if ok && ship.dockedstatus == hal.DOCKED {
+				docked++
+			}
+		}
+
+		if docked == 0 {
+			continue
+		}
+
+		planet.current_production += hal.Production(docked)
+
+		for planet.current_production >= hal.PRODUCTION_PER_SHIP {
+
+			x, y, ok := self.spawn_location(planet)
+			if ok == false {
+				break
+			}
+
+			planet.current_production -= hal.PRODUCTION_PER_SHIP
+
+			self.ships = append(self.ships, &SimShip{
+				SimEntity: SimEntity{
+					x: x,
+					y: y,
+					radius: hal.SHIP_RADIUS,
+				},
+				ship_state: ALIVE,
+				weapon_state: READY,
+				dockedstatus: hal.UNDOCKED,
+				docked_planet: -1,
+				owner: planet.owner,
+				hp: hal.MAX_SHIP_HEALTH,
+				id: self.next_sid,
+			})
+
+			self.next_sid++
+		}
+	}
+}
+
+func (self *FullSim) spawn_location(planet *SimPlanet) (float64, float64, bool) {
+
+	occupied := func(x, y float64) bool {
+		for _, ship := range self.ships {
+			if hal.Dist(x, y, ship.x, ship.y) <= hal.SPAWN_FREE_DIST {
+				return true
+			}
+		}
+		return false
+	}
+
+	return hal.SpawnLocation(planet.x, planet.y, planet.radius, int(self.width), int(self.height), occupied)
+}
+
+func (self *FullSim) apply_orders() {
+
+	dock_requests := make(map[int][]*SimShip)		// Planet ID --> ships wanting to dock there
+
+	for _, ship := range self.ships {
+
+		ship.vel_x, ship.vel_y = 0, 0
+		ship.weapon_state = READY
+
+		order, ok := self.orders[ship.id]
+		if ok == false {
+			continue
+		}
+
+		switch order.Type {
+
+		case hal.THRUST:
+
+			if ship.dockedstatus != hal.UNDOCKED {
+				continue
+			}
+
+			speed := hal.Min(hal.Max(order.Speed, 0), hal.MAX_SPEED)
+			ship.vel_x, ship.vel_y = hal.Projection(0, 0, float64(speed), order.Angle)
+
+		case hal.DOCK:
+
+			planet, ok := self.GetPlanet(order.Plid)
+
+			if ok == false || ship.dockedstatus != hal.UNDOCKED {
+				continue
+			}
+
+			if hal.InDockingRange(ship.x, ship.y, planet.x, planet.y, planet.radius) == false {
+				continue
+			}
+
+			dock_requests[planet.id] = append(dock_requests[planet.id], ship)
+
+		case hal.UNDOCK:
+
+			if ship.dockedstatus != hal.DOCKED {
+				continue
+			}
+
+			ship.dockedstatus = hal.UNDOCKING
+			ship.docking_progress = hal.DOCK_TURNS
+		}
+	}
+
+	self.resolve_docks(dock_requests)
+}
+
+func (self *FullSim) resolve_docks(dock_requests map[int][]*SimShip) {
+
+	var plids []int
+	for plid, _ := range dock_requests {
+		plids = append(plids, plid)
+	}
+	sort.Ints(plids)
+
+	for _, plid := range plids {
+
+		planet, _ := self.GetPlanet(plid)
+		ships := dock_requests[plid]
+
+		sort.SliceStable(ships, func(a, b int) bool {
+			return ships[a].owner < ships[b].owner			// The engine takes players' orders in player order.
+		})
+
+		var requests []*hal.DockRequest
+		for _, ship := range ships {
+			requests = append(requests, &hal.DockRequest{Pid: ship.owner, Sid: ship.id})
+		}
+
+		planet.owner = hal.ResolveDocks(planet.owner, planet.docking_spots - len(planet.docked), requests)
+
+		for i, ship := range ships {
+
+			if requests[i].Refusal != "" {
+				continue
+			}
+
+			planet.docked = append(planet.docked, ship.id)
+
+			ship.dockedstatus = hal.DOCKING
+			ship.docked_planet = planet.id
+			ship.docking_progress = hal.DOCK_TURNS
+		}
+	}
+}
+
+func (self *FullSim) process_docking() {
+
+	// Pushes docking status forward, as the parser's fudge_dock_status() does.
+
+	for _, ship := range self.ships {
+
+		switch ship.dockedstatus {
+
+		case hal.DOCKING:
+
+			ship.docking_progress--
+			if ship.docking_progress <= 0 {
+				ship.docking_progress = 0
+				ship.dockedstatus = hal.DOCKED
+			}
+
+		case hal.UNDOCKING:
+
+			ship.docking_progress--
+			if ship.docking_progress <= 0 {
+				planet, ok := self.GetPlanet(ship.docked_planet)
+				if ok {
+					planet.remove_docked(ship.id)
+				}
+				ship.docking_progress = 0
+				ship.dockedstatus = hal.UNDOCKED
+				ship.docked_planet = -1
+			}
+		}
+	}
+}
+
+// ---------------------------------------
+
+func (self *FullSim) process_movement() {
+
+	var possible_events []*PossibleEvent
+
+	for i, ship_a := range self.ships {
+
+		for _, ship_b := range self.ships[i+1:] {
+
+			if ship_a.owner != ship_b.owner {
+				t, ok := CollisionTime(hal.ATTACK_RANGE, &ship_a.SimEntity, &ship_b.SimEntity)
+				if ok && t >= 0 && t <= 1 {
+					possible_events = append(possible_events, &PossibleEvent{ship_a, ship_b, nil, t, ATTACK})
+				}
+			}
+
+			t, ok := CollisionTime(hal.SHIP_RADIUS * 2, &ship_a.SimEntity, &ship_b.SimEntity)
+			if ok && t >= 0 && t <= 1 {
+				possible_events = append(possible_events, &PossibleEvent{ship_a, ship_b, nil, t, SHIP_COLLISION})
+			}
+		}
+
+		for _, planet := range self.planets {
+			t, ok := CollisionTime(planet.radius + hal.SHIP_RADIUS, &ship_a.SimEntity, &planet.SimEntity)
+			if ok && t >= 0 && t <= 1 {
+				possible_events = append(possible_events, &PossibleEvent{ship_a, nil, planet, t, PLANET_COLLISION})
+			}
+		}
+	}
+
+	sort.SliceStable(possible_events, func(a, b int) bool {
+		return possible_events[a].t < possible_events[b].t
+	})
+
+	// Events at the same moment are simultaneous: nothing dies until the whole group is handled.
+
+	for i := 0; i < len(possible_events); {
+
+		j := i
+		for j < len(possible_events) && possible_events[j].t == possible_events[i].t {
+			j++
+		}
+
+		self.resolve_events(possible_events[i:j])
+		i = j
+	}
+
+	for _, ship := range self.ships {
+
+		if ship.ship_state == DEAD {
+			continue
+		}
+
+		ship.x += ship.vel_x
+		ship.y += ship.vel_y
+
+		if ship.x < 0 || ship.y < 0 || ship.x >= self.width || ship.y >= self.height {
+			self.kill(ship)
+		}
+	}
+
+	self.remove_dead()
+}
+
+func (self *FullSim) resolve_events(group []*PossibleEvent) {
+
+	t := group[0].t
+
+	for _, event := range group {
+
+		switch event.what {
+
+		case SHIP_COLLISION:
+
+			if event.ship_a.ship_state == DEAD || event.ship_b.ship_state == DEAD {
+				continue
+			}
+
+			event.ship_a.pending_damage += event.ship_a.hp
+			event.ship_b.pending_damage += event.ship_b.hp
+
+		case PLANET_COLLISION:
+
+			if event.ship_a.ship_state == DEAD || event.planet.hp <= 0 {
+				continue
+			}
+
+			event.planet.hp -= event.ship_a.hp				// Planets can't be damaged twice "at once" in any way that matters.
+			event.ship_a.pending_damage += event.ship_a.hp
+
+		case ATTACK:
+
+			// Whoever can fire, fires at everything in range at this moment.
+
+			for _, shooter := range []*SimShip{event.ship_a, event.ship_b} {
+
+				if shooter.ship_state == DEAD || shooter.weapon_state == SPENT || shooter.dockedstatus != hal.UNDOCKED {
+					continue
+				}
+
+				sx, sy := shooter.x + shooter.vel_x * t, shooter.y + shooter.vel_y * t
+
+				for _, other := range self.ships {
+					if other.ship_state != DEAD && other.owner != shooter.owner {
+						ox, oy := other.x + other.vel_x * t, other.y + other.vel_y * t
+						if hal.Dist(sx, sy, ox, oy) <= hal.ATTACK_RANGE + 0.0001 {
+							shooter.actual_targets = append(shooter.actual_targets, other)
+						}
+					}
+				}
+
+				if len(shooter.actual_targets) > 0 {
+					shooter.weapon_state = SPENT
+				}
+			}
+		}
+	}
+
+	for _, ship := range self.ships {
+		if len(ship.actual_targets) > 0 {
+			damage := hal.WEAPON_DAMAGE / len(ship.actual_targets)
+			for _, target := range ship.actual_targets {
+				target.pending_damage += damage
+			}
+			ship.actual_targets = nil
+		}
+	}
+
+	for _, ship := range self.ships {
+		if ship.pending_damage > 0 {
+			ship.hp -= ship.pending_damage
+			ship.pending_damage = 0
+			if ship.hp <= 0 {
+				self.kill(ship)
+			}
+		}
+	}
+
+	for _, planet := range self.planets {
+		if planet.hp <= 0 && planet.exploded == false {
+			self.explode(planet, t)
+		}
+	}
+}
+
+func (self *FullSim) explode(planet *SimPlanet, t float64) {
+
+	planet.exploded = true
+	planet.hp = 0
+
+	for _, sid := range planet.docked {
+		ship, ok := self.GetShip(sid)
+		if ok {
+			ship.docked_planet = -1					// So kill() doesn't touch the planet we're iterating over.
+			self.kill(ship)
+		}
+	}
+
+	planet.docked = nil
+
+	// Damage falls off with distance from the planet's surface, see hal.ExplosionDamage()...
+
+	for _, ship := range self.ships {
+
+		if ship.ship_state == DEAD {
+			continue
+		}
+
+		x, y := ship.x + ship.vel_x * t, ship.y + ship.vel_y * t
+		dist := hal.Dist(x, y, planet.x, planet.y) - planet.radius
+
+		if dist < hal.EXPLOSION_RADIUS {
+			ship.hp -= hal.ExplosionDamage(dist)
+			if ship.hp <= 0 {
+				self.kill(ship)
+			}
+		}
+	}
+}
+
+func (self *FullSim) kill(ship *SimShip) {
+
+	ship.hp = 0
+	ship.ship_state = DEAD
+
+	if ship.docked_planet != -1 {
+		planet, ok := self.GetPlanet(ship.docked_planet)
+		if ok {
+			planet.remove_docked(ship.id)
+		}
+		ship.docked_planet = -1
+	}
+}
+
+func (self *FullSim) remove_dead() {
+
+	var ships []*SimShip
+	for _, ship := range self.ships {
+		if ship.ship_state != DEAD {
+			ships = append(ships, ship)
+		}
+	}
+	self.ships = ships
+
+	var planets []*SimPlanet
+	for _, planet := range self.planets {
+		if planet.exploded == false {
+			planets = append(planets, planet)
+		}
+	}
+	self.planets = planets
+}
+
+// ---------------------------------------
+
+func (self *SimPlanet) remove_docked(sid int) {
+
+	for i, docked := range self.docked {
+		if docked == sid {
+			self.docked = append(self.docked[:i], self.docked[i+1:]...)
+			break
+		}
+	}
+
+	if len(self.docked) == 0 {
+		self.owner = -1
+	}
+}
+
+func (self *SimShip) Id() int { return self.id }
+func (self *SimShip) Owner() int { return self.owner }
+func (self *SimShip) X() float64 { return self.x }
+func (self *SimShip) Y() float64 { return self.y }
+func (self *SimShip) HP() int { return self.hp }
+func (self *SimShip) DockedStatus() hal.DockedStatus { return self.dockedstatus }
+func (self *SimShip) DockedPlanet() int { return self.docked_planet }
+
+func (self *SimPlanet) Id() int { return self.id }
+func (self *SimPlanet) Owner() int { return self.owner }
+func (self *SimPlanet) X() float64 { return self.x }
+func (self *SimPlanet) Y() float64 { return self.y }
+func (self *SimPlanet) HP() int { return self.hp }
+func (self *SimPlanet) Radius() float64 { return self.radius }
+func (self *SimPlanet) DockedShips() []int { return self.docked }
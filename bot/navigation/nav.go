@@ -7,9 +7,11 @@ import (
 )
 
 // In all calls, the NavStacker is just used for dumping info into its NavStack for debugging...
+// (and the waypoints we route through, for drawing).
 
 type NavStacker interface {
 	AddToNavStack(format_string string, args ...interface{})
+	AddWaypoint(x, y float64)
 	GetGame() *hal.Game
 }
 
@@ -61,10 +63,6 @@ func GetCourseRecursive(ship *hal.Ship, target hal.Entity, avoid_list []hal.Enti
 	// Try to navigate to (collide with) the target, but avoiding the list of entites,
 	// which could include the target. Returns: speed, angle, error
 
-	const (
-		DODGE_MARGIN = 1.5
-	)
-
 	distance := ship.Dist(target)
 
 	if distance < 0.5 {
@@ -93,6 +91,11 @@ func GetCourseRecursive(ship *hal.Ship, target hal.Entity, avoid_list []hal.Enti
 		return 0, 0, fmt.Errorf("GetCourseRecursive(): exceeded max depth")
 	}
 
+	if ns.GetGame().Budget().Expired() {
+		ns.AddToNavStack("GetCourseRecursive(): out of time")
+		return 0, 0, fmt.Errorf("GetCourseRecursive(): out of time")
+	}
+
 	// Reset our nav side iff the colliding object is a planet...
 
 	if c.Type() == hal.PLANET {
@@ -108,9 +111,10 @@ func GetCourseRecursive(ship *hal.Ship, target hal.Entity, avoid_list []hal.Enti
 		waypoint_angle = degrees - 90
 	}
 
-	waypointx, waypointy := hal.Projection(c.GetX(), c.GetY(), c.GetRadius() + DODGE_MARGIN, waypoint_angle)
+	waypointx, waypointy := hal.Projection(c.GetX(), c.GetY(), c.GetRadius() + ns.GetGame().Params().DodgeMargin, waypoint_angle)
 	p := &hal.Point{waypointx, waypointy}
 
+	ns.AddWaypoint(waypointx, waypointy)
 	ns.AddToNavStack("GetCourseRecursive(): angle: %v; collision: %v; recursing with %v", degrees, c, p)
 	return GetCourseRecursive(ship, p, avoid_list, depth - 1, side, ns)
 }
@@ -13,13 +13,14 @@ const (
 
 type Pilot struct {
 	*hal.Ship
-	Plan				string						// Our planned order, valid for 1 turn only.
+	Plan				hal.Order					// Our planned order, valid for 1 turn only. The zero Order is no plan.
 	Message				int							// Message for this turn. -1 for no message.
-	HasExecuted			bool						// Have we actually "sent" the order? (Placed it in the game.orders map.)
+	HasExecuted			bool						// Have we actually "sent" the order? (Given it to the game with SetOrder().)
 	Game				*hal.Game
 	Target				hal.Entity					// Use the hal.Nothing struct for no target.
 	EnemyApproachDist	float64
 	NavStack			[]string
+	Waypoints			[]*hal.Point				// Where navigation routed us around things this turn (for drawing).
 	Inhibition			float64
 	Locked				bool						// Whether Target can change. Use super-sparingly.
 	DangerShips			[]*hal.Ship					// Enemy ships that could potentially shoot us this turn.
@@ -47,6 +48,10 @@ func (self *Pilot) AddToNavStack(format_string string, args ...interface{}) {
 	self.NavStack = append(self.NavStack, s)
 }
 
+func (self *Pilot) AddWaypoint(x, y float64) {
+	self.Waypoints = append(self.Waypoints, &hal.Point{X: x, Y: y})
+}
+
 func (self *Pilot) LogNavStack() {
 	self.Game.Log("%v Nav Stack:", self)
 	for _, s := range self.NavStack {
@@ -60,9 +65,9 @@ func (self *Pilot) Log(format_string string, args ...interface{}) {
 }
 
 func (self *Pilot) ResetPlan() {
-	self.Plan = ""
+	self.Plan = hal.Order{}
 	self.HasExecuted = false
-	self.Game.RawOrder(self.Id, "")
+	self.Game.ClearOrder(self.Ship)
 	self.Fleeing = false
 }
 
@@ -77,6 +82,7 @@ func (self *Pilot) ResetAndUpdate() bool {				// Return true if we still exist.
 	self.ResetPlan()
 
 	self.NavStack = nil
+	self.Waypoints = nil
 	self.Message = -1
 	self.EnemyApproachDist = DEFAULT_ENEMY_SHIP_APPROACH_DIST
 	self.Inhibition = 0
@@ -116,7 +122,7 @@ func (self *Pilot) ResetAndUpdate() bool {				// Return true if we still exist.
 }
 
 func (self *Pilot) HasStationaryPlan() bool {		// true iff we DO have a plan, which doesn't move us.
-	if self.Plan == "" {
+	if self.Plan.IsNone() {
 		return false
 	}
 	speed, _ := self.CourseFromPlan()
@@ -124,7 +130,7 @@ func (self *Pilot) HasStationaryPlan() bool {		// true iff we DO have a plan, wh
 }
 
 func (self *Pilot) CourseFromPlan() (int, int) {
-	return hal.CourseFromString(self.Plan)
+	return self.Plan.Course()
 }
 
 func (self *Pilot) HasTarget() bool {				// We don't use nil ever, so we can e.g. call hal.Type()
@@ -142,32 +148,31 @@ func (self *Pilot) TargetDist() float64 {
 // -------------------------------------------------------------------
 
 func (self *Pilot) PlanThrust(speed, degrees int) {
-	for degrees < 0 { degrees += 360 }
-	degrees %= 360
-	self.Plan = fmt.Sprintf("t %d %d %d", self.Id, speed, degrees)
+	self.Plan = hal.ThrustOrder(self.Id, speed, degrees)
 }
 
 func (self *Pilot) PlanDock(planet *hal.Planet) {
-	self.Plan = fmt.Sprintf("d %d %d", self.Id, planet.Id)
+	self.Plan = hal.DockOrder(self.Id, planet.Id)
 }
 
 func (self *Pilot) PlanUndock() {
-	self.Plan = fmt.Sprintf("u %d", self.Id)
+	self.Plan = hal.UndockOrder(self.Id)
 }
 
 // -------------------------------------------------------------------
 
 func (self *Pilot) ExecutePlan() {
-	if self.Plan == "" {
+	if self.Plan.IsNone() {
 		self.PlanThrust(0, 0)
 	}
-	self.Game.RawOrder(self.Id, self.Plan)
-	self.Game.SetMessage(self.Ship, self.Message)			// Fails silently if message < 0 or > 180
+	order := self.Plan
+	order.Message = self.Message							// Ignored if < 0 or > 180
+	self.Game.SetOrder(order)
 	self.HasExecuted = true
 }
 
 func (self *Pilot) ExecutePlanIfStationary() {
-	speed, _ := hal.CourseFromString(self.Plan)
+	speed, _ := self.CourseFromPlan()
 	if speed == 0 {
 		self.ExecutePlan()
 	}
@@ -175,7 +180,7 @@ func (self *Pilot) ExecutePlanIfStationary() {
 
 func (self *Pilot) SlowPlanDown() {
 
-	speed, degrees := hal.CourseFromString(self.Plan)
+	speed, degrees := self.CourseFromPlan()
 
 	if speed <= 1 {											// Don't slow our plan to zero, which is like having no plan.
 		return
@@ -11,10 +11,16 @@ func ExecuteSafely(mobile_pilots []*Pilot) {
 	}
 
 	game := mobile_pilots[0].Game
+	safe_range := game.Params().SafeMoveRange
 
 	// Assumption: we have already taken steps to ensure that any ship not included in the mobile_pilots
 	// is avoided, i.e. those ships were explicitly avoided in the earlier navigation search.
 
+	pilot_map := make(map[int]*Pilot)			// Ship ID --> pilot, for looking up the grid's results
+	for _, pilot := range mobile_pilots {
+		pilot_map[pilot.Id] = pilot
+	}
+
 	for n := 0; n < 11; n++ {
 
 		total_executes := 0
@@ -39,13 +45,15 @@ func ExecuteSafely(mobile_pilots []*Pilot) {
 				continue
 			}
 
-			for _, pilot2 := range mobile_pilots {
+			for _, ship2 := range game.Grid().ShipsNear(pilot1.X, pilot1.Y, safe_range + 0.001) {
+
+				pilot2, ok := pilot_map[ship2.Id]
 
-				if pilot2 == pilot1 {
+				if ok == false || pilot2 == pilot1 {
 					continue
 				}
 
-				if pilot2.Dist(pilot1) > 15 {
+				if pilot2.Dist(pilot1) > safe_range {
 					continue
 				}
 
@@ -57,7 +65,7 @@ func ExecuteSafely(mobile_pilots []*Pilot) {
 				pilot2_angle := 0
 
 				if pilot2.HasExecuted {
-					pilot2_speed, pilot2_angle = hal.CourseFromString(game.CurrentOrder(pilot2.Ship))
+					pilot2_speed, pilot2_angle = game.CurrentOrder(pilot2.Ship).Course()
 				}
 
 				msg2 := pilot2.Message
@@ -0,0 +1,166 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	hal "../core"
+)
+
+// A Playback feeds a replay's frames, one at a time, to a core.Game, in the exact
+// text format the Halite engine used. Since everything goes through Game.Parse(),
+// the Game ends up in the same state as it would have been in the real game
+// (inferred velocities, birth turns, lastownerMap, PredictTimeZero flags, etc).
+
+type Playback struct {
+	replay				*Replay
+	pid					int
+	next				int					// Index of the next frame to feed
+	feed				*bytes.Buffer
+	game				*hal.Game
+}
+
+func NewPlayback(replay *Replay, pid int) (*Playback, error) {
+
+	if pid < 0 || pid >= replay.NumPlayers {
+		return nil, fmt.Errorf("NewPlayback(): no player %d in a %d player replay", pid, replay.NumPlayers)
+	}
+
+	ret := &Playback{
+		replay: replay,
+		pid: pid,
+		feed: new(bytes.Buffer),
+	}
+
+	ret.feed.WriteString(replay.InitString(pid))
+
+	game, err := hal.ReadGame(ret.feed, ioutil.Discard)
+	if err != nil {
+		return nil, fmt.Errorf("NewPlayback(): %v", err)
+	}
+
+	ret.game = game
+
+	return ret, nil
+}
+
+// Game returns the Game being fed. Before the first call to Next(), it is in
+// the init stage (turn -1), i.e. the state a bot sees when NewGame() returns.
+
+func (self *Playback) Game() *hal.Game {
+	return self.game
+}
+
+// Next parses the next frame. Afterwards, Game().Turn() is the turn of that frame.
+
+func (self *Playback) Next() error {
+
+	if self.next >= len(self.replay.Frames) {
+		return fmt.Errorf("Next(): replay has no frame %d", self.next)
+	}
+
+	self.feed.WriteString(self.replay.FrameString(self.next))
+
+	err := self.game.Parse()
+	if err != nil {
+		return fmt.Errorf("Next(): frame %d: %v", self.next, err)
+	}
+
+	self.next++
+	return nil
+}
+
+// GameAt returns a Game for the given player, as it was after parsing the frame
+// of the given turn, i.e. just before the bot would have made its moves.
+
+func (self *Replay) GameAt(pid, turn int) (*hal.Game, error) {
+
+	if turn < 0 || turn >= len(self.Frames) {
+		return nil, fmt.Errorf("GameAt(): turn %d not in replay (%d frames)", turn, len(self.Frames))
+	}
+
+	playback, err := NewPlayback(self, pid)
+	if err != nil {
+		return nil, err
+	}
+
+	for playback.Game().Turn() < turn {
+		err = playback.Next()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return playback.Game(), nil
+}
+
+// ---------------------------------------
+
+func (self *Replay) InitString(pid int) string {
+	return fmt.Sprintf("%d\n%d %d\n%s", pid, self.Width, self.Height, self.FrameString(0))
+}
+
+func (self *Replay) FrameString(n int) string {
+
+	frame := self.Frames[n]
+
+	var tokens []string
+
+	add := func(format string, args ...interface{}) {
+		tokens = append(tokens, fmt.Sprintf(format, args...))
+	}
+
+	add("%d", self.NumPlayers)
+
+	for pid := 0; pid < self.NumPlayers; pid++ {
+
+		ships := frame.ShipsOwnedBy(pid)
+
+		add("%d %d", pid, len(ships))
+
+		for _, ship := range ships {
+
+			docked_planet, progress := 0, 0
+
+			if ship.DockedStatus != hal.UNDOCKED {
+				docked_planet = ship.DockedPlanet
+			}
+			if ship.DockedStatus == hal.DOCKING || ship.DockedStatus == hal.UNDOCKING {
+				progress = ship.TurnsLeft
+			}
+
+			add("%d %s %s %d %s %s %d %d %d %d",
+				ship.Id, float_token(ship.X), float_token(ship.Y), ship.HP, float_token(ship.VelX), float_token(ship.VelY),
+				int(ship.DockedStatus), docked_planet, progress, ship.Cooldown)
+		}
+	}
+
+	add("%d", len(frame.Planets))
+
+	for _, planet := range frame.Planets {
+
+		info := self.Planets[planet.Id]
+
+		owned, owner := 0, 0
+		if planet.Owner != -1 {
+			owned, owner = 1, planet.Owner
+		}
+
+		add("%d %s %s %d %s %d %d %d %d %d %d",
+			planet.Id, float_token(info.X), float_token(info.Y), planet.HP, float_token(info.Radius),
+			info.DockingSpots, planet.CurrentProduction, planet.RemainingProduction, owned, owner, len(planet.DockedShips))
+
+		for _, sid := range planet.DockedShips {
+			add("%d", sid)
+		}
+	}
+
+	return strings.Join(tokens, " ") + "\n"
+}
+
+func float_token(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
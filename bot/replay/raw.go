@@ -0,0 +1,514 @@
+package replay
+
+import (
+	"sort"
+	"strconv"
+
+	hal "../core"
+)
+
+// The JSON layout of a .hlt file, as written by the official engine.
+
+type raw_replay struct {
+	Constants			Constants							`json:"constants"`
+	EngineVersion		string								`json:"engine_version"`
+	Frames				[]*raw_frame						`json:"frames"`
+	Height				int									`json:"height"`
+	MapGenerator		string								`json:"map_generator"`
+	Moves				[]map[string][]map[string]*raw_move	`json:"moves"`
+	NumFrames			int									`json:"num_frames"`
+	NumPlayers			int									`json:"num_players"`
+	Planets				[]*raw_planet_info					`json:"planets"`
+	PlayerNames			[]string							`json:"player_names"`
+	POI					[]interface{}						`json:"poi"`
+	Seed				int64								`json:"seed"`
+	Stats				map[string]*raw_stats				`json:"stats"`
+	Version				int									`json:"version"`
+	Width				int									`json:"width"`
+}
+
+type raw_frame struct {
+	Events				[]*raw_event						`json:"events"`
+	Planets				map[string]*raw_planet				`json:"planets"`
+	Ships				map[string]map[string]*raw_ship		`json:"ships"`
+}
+
+type raw_planet_info struct {
+	DockingSpots		int				`json:"docking_spots"`
+	Health				int				`json:"health"`
+	Id					int				`json:"id"`
+	Production			int				`json:"production"`
+	R					float64			`json:"r"`
+	X					float64			`json:"x"`
+	Y					float64			`json:"y"`
+}
+
+type raw_planet struct {
+	CurrentProduction	int				`json:"current_production"`
+	DockedShips			[]int			`json:"docked_ships"`
+	Health				int				`json:"health"`
+	Id					int				`json:"id"`
+	Owner				*int			`json:"owner"`
+	RemainingProduction	int				`json:"remaining_production"`
+}
+
+type raw_docking struct {
+	PlanetId			*int			`json:"planet_id,omitempty"`
+	Status				string			`json:"status"`
+	TurnsLeft			*int			`json:"turns_left,omitempty"`
+}
+
+type raw_ship struct {
+	Cooldown			int				`json:"cooldown"`
+	Docking				raw_docking		`json:"docking"`
+	Health				int				`json:"health"`
+	Id					int				`json:"id"`
+	Owner				int				`json:"owner"`
+	VelX				float64			`json:"vel_x"`
+	VelY				float64			`json:"vel_y"`
+	X					float64			`json:"x"`
+	Y					float64			`json:"y"`
+}
+
+type raw_entity struct {
+	Id					int				`json:"id"`
+	Owner				*int			`json:"owner,omitempty"`
+	Type				string			`json:"type"`
+}
+
+type raw_event struct {
+	Entity				raw_entity		`json:"entity"`
+	Event				string			`json:"event"`
+	Planet				*raw_entity		`json:"planet,omitempty"`
+	PlanetX				*float64		`json:"planet_x,omitempty"`
+	PlanetY				*float64		`json:"planet_y,omitempty"`
+	Radius				*float64		`json:"radius,omitempty"`
+	TargetLocations		[]raw_entity	`json:"target_locations,omitempty"`
+	Targets				[]raw_entity	`json:"targets,omitempty"`
+	Time				float64			`json:"time"`
+	X					float64			`json:"x"`
+	Y					float64			`json:"y"`
+}
+
+type raw_move struct {
+	Angle				*int			`json:"angle,omitempty"`
+	Magnitude			*int			`json:"magnitude,omitempty"`
+	Owner				int				`json:"owner"`
+	PlanetId			*int			`json:"planet_id,omitempty"`
+	QueueNumber			int				`json:"queue_number"`
+	ShipId				int				`json:"shipId"`
+	Type				string			`json:"type"`
+}
+
+type raw_stats struct {
+	AverageFrameResponseTime	float64		`json:"average_frame_response_time"`
+	DamageDealt					int			`json:"damage_dealt"`
+	InitResponseTime			float64		`json:"init_response_time"`
+	LastFrameAlive				int			`json:"last_frame_alive"`
+	MaxFrameResponseTime		float64		`json:"max_frame_response_time"`
+	Rank						int			`json:"rank"`
+	TotalShipCount				int			`json:"total_ship_count"`
+}
+
+// ---------------------------------------
+
+var docking_statuses = map[string]hal.DockedStatus{
+	"undocked": hal.UNDOCKED,
+	"docking": hal.DOCKING,
+	"docked": hal.DOCKED,
+	"undocking": hal.UNDOCKING,
+}
+
+func (self *raw_replay) convert() *Replay {
+
+	ret := &Replay{
+		Version: self.Version,
+		EngineVersion: self.EngineVersion,
+		MapGenerator: self.MapGenerator,
+		Seed: self.Seed,
+		Width: self.Width,
+		Height: self.Height,
+		NumPlayers: self.NumPlayers,
+		PlayerNames: self.PlayerNames,
+		Constants: self.Constants,
+	}
+
+	for _, p := range self.Planets {
+		ret.Planets = append(ret.Planets, &PlanetInfo{
+			Id: p.Id,
+			X: p.X,
+			Y: p.Y,
+			Radius: p.R,
+			DockingSpots: p.DockingSpots,
+			Health: p.Health,
+			Production: p.Production,
+		})
+	}
+
+	sort.Slice(ret.Planets, func(a, b int) bool {
+		return ret.Planets[a].Id < ret.Planets[b].Id
+	})
+
+	for _, f := range self.Frames {
+		ret.Frames = append(ret.Frames, f.convert())
+	}
+
+	for _, turn_moves := range self.Moves {
+
+		var moves []*Move
+
+		for _, queues := range turn_moves {
+			for _, queue := range queues {
+				for _, m := range queue {
+					moves = append(moves, m.convert())
+				}
+			}
+		}
+
+		sort.Slice(moves, func(a, b int) bool {
+			if moves[a].Owner != moves[b].Owner {
+				return moves[a].Owner < moves[b].Owner
+			}
+			return moves[a].ShipId < moves[b].ShipId
+		})
+
+		ret.Moves = append(ret.Moves, moves)
+	}
+
+	ret.Stats = make([]*PlayerStats, self.NumPlayers)
+
+	for key, s := range self.Stats {
+		pid, err := strconv.Atoi(key)
+		if err != nil || pid < 0 || pid >= self.NumPlayers {
+			continue
+		}
+		ret.Stats[pid] = &PlayerStats{
+			Rank: s.Rank,
+			LastFrameAlive: s.LastFrameAlive,
+			TotalShipCount: s.TotalShipCount,
+			DamageDealt: s.DamageDealt,
+			InitResponseTime: s.InitResponseTime,
+			AverageResponseTime: s.AverageFrameResponseTime,
+			MaxResponseTime: s.MaxFrameResponseTime,
+		}
+	}
+
+	for pid, s := range ret.Stats {
+		if s == nil {
+			ret.Stats[pid] = new(PlayerStats)
+		}
+	}
+
+	return ret
+}
+
+func (self *raw_frame) convert() *Frame {
+
+	ret := new(Frame)
+
+	for _, player_ships := range self.Ships {
+		for _, s := range player_ships {
+
+			ship := &Ship{
+				Id: s.Id,
+				Owner: s.Owner,
+				X: s.X,
+				Y: s.Y,
+				VelX: s.VelX,
+				VelY: s.VelY,
+				HP: s.Health,
+				Cooldown: s.Cooldown,
+				DockedStatus: docking_statuses[s.Docking.Status],
+				DockedPlanet: -1,
+			}
+
+			if s.Docking.PlanetId != nil {
+				ship.DockedPlanet = *s.Docking.PlanetId
+			}
+			if s.Docking.TurnsLeft != nil {
+				ship.TurnsLeft = *s.Docking.TurnsLeft
+			}
+
+			ret.Ships = append(ret.Ships, ship)
+		}
+	}
+
+	sort.Slice(ret.Ships, func(a, b int) bool {
+		return ret.Ships[a].Id < ret.Ships[b].Id
+	})
+
+	for _, p := range self.Planets {
+
+		planet := &Planet{
+			Id: p.Id,
+			HP: p.Health,
+			Owner: -1,
+			DockedShips: p.DockedShips,
+			CurrentProduction: p.CurrentProduction,
+			RemainingProduction: p.RemainingProduction,
+		}
+
+		if p.Owner != nil {
+			planet.Owner = *p.Owner
+		}
+
+		ret.Planets = append(ret.Planets, planet)
+	}
+
+	sort.Slice(ret.Planets, func(a, b int) bool {
+		return ret.Planets[a].Id < ret.Planets[b].Id
+	})
+
+	for _, e := range self.Events {
+		ret.Events = append(ret.Events, e.convert())
+	}
+
+	return ret
+}
+
+func (self raw_entity) convert() EntityRef {
+	ret := EntityRef{Type: self.Type, Id: self.Id, Owner: -1}
+	if self.Owner != nil {
+		ret.Owner = *self.Owner
+	}
+	return ret
+}
+
+func (self *raw_event) convert() *Event {
+
+	ret := &Event{
+		Type: EventType(self.Event),
+		Time: self.Time,
+		X: self.X,
+		Y: self.Y,
+		Entity: self.Entity.convert(),
+	}
+
+	for _, target := range self.Targets {
+		ret.Targets = append(ret.Targets, target.convert())
+	}
+
+	if self.Radius != nil { ret.Radius = *self.Radius }
+	if self.Planet != nil { ret.Planet = self.Planet.convert() }
+	if self.PlanetX != nil { ret.PlanetX = *self.PlanetX }
+	if self.PlanetY != nil { ret.PlanetY = *self.PlanetY }
+
+	return ret
+}
+
+func (self *raw_move) convert() *Move {
+
+	ret := &Move{
+		Type: MoveType(self.Type),
+		Owner: self.Owner,
+		ShipId: self.ShipId,
+		QueueNumber: self.QueueNumber,
+	}
+
+	if self.Magnitude != nil { ret.Magnitude = *self.Magnitude }
+	if self.Angle != nil { ret.Angle = *self.Angle }
+	if self.PlanetId != nil { ret.PlanetId = *self.PlanetId }
+
+	return ret
+}
+
+// ---------------------------------------
+// And back again, for the writer...
+
+var docking_status_names = map[hal.DockedStatus]string{
+	hal.UNDOCKED: "undocked",
+	hal.DOCKING: "docking",
+	hal.DOCKED: "docked",
+	hal.UNDOCKING: "undocking",
+}
+
+func unconvert_replay(r *Replay) *raw_replay {
+
+	ret := &raw_replay{
+		Constants: r.Constants,
+		EngineVersion: r.EngineVersion,
+		Height: r.Height,
+		MapGenerator: r.MapGenerator,
+		NumFrames: len(r.Frames),
+		NumPlayers: r.NumPlayers,
+		PlayerNames: r.PlayerNames,
+		POI: []interface{}{},
+		Seed: r.Seed,
+		Stats: make(map[string]*raw_stats),
+		Version: r.Version,
+		Width: r.Width,
+	}
+
+	for _, p := range r.Planets {
+		ret.Planets = append(ret.Planets, &raw_planet_info{
+			DockingSpots: p.DockingSpots,
+			Health: p.Health,
+			Id: p.Id,
+			Production: p.Production,
+			R: p.Radius,
+			X: p.X,
+			Y: p.Y,
+		})
+	}
+
+	for _, f := range r.Frames {
+		ret.Frames = append(ret.Frames, unconvert_frame(f))
+	}
+
+	for _, moves := range r.Moves {
+
+		turn_moves := make(map[string][]map[string]*raw_move)
+
+		for _, m := range moves {
+
+			key := strconv.Itoa(m.Owner)
+
+			for len(turn_moves[key]) <= m.QueueNumber {
+				turn_moves[key] = append(turn_moves[key], make(map[string]*raw_move))
+			}
+
+			turn_moves[key][m.QueueNumber][strconv.Itoa(m.ShipId)] = unconvert_move(m)
+		}
+
+		ret.Moves = append(ret.Moves, turn_moves)
+	}
+
+	for pid, s := range r.Stats {
+		if s != nil {
+			ret.Stats[strconv.Itoa(pid)] = &raw_stats{
+				AverageFrameResponseTime: s.AverageResponseTime,
+				DamageDealt: s.DamageDealt,
+				InitResponseTime: s.InitResponseTime,
+				LastFrameAlive: s.LastFrameAlive,
+				MaxFrameResponseTime: s.MaxResponseTime,
+				Rank: s.Rank,
+				TotalShipCount: s.TotalShipCount,
+			}
+		}
+	}
+
+	return ret
+}
+
+func unconvert_frame(f *Frame) *raw_frame {
+
+	ret := &raw_frame{
+		Planets: make(map[string]*raw_planet),
+		Ships: make(map[string]map[string]*raw_ship),
+	}
+
+	for _, s := range f.Ships {
+
+		ship := &raw_ship{
+			Cooldown: s.Cooldown,
+			Docking: raw_docking{Status: docking_status_names[s.DockedStatus]},
+			Health: s.HP,
+			Id: s.Id,
+			Owner: s.Owner,
+			VelX: s.VelX,
+			VelY: s.VelY,
+			X: s.X,
+			Y: s.Y,
+		}
+
+		if s.DockedStatus != hal.UNDOCKED {
+			planet_id := s.DockedPlanet
+			ship.Docking.PlanetId = &planet_id
+		}
+		if s.DockedStatus == hal.DOCKING || s.DockedStatus == hal.UNDOCKING {
+			turns_left := s.TurnsLeft
+			ship.Docking.TurnsLeft = &turns_left
+		}
+
+		key := strconv.Itoa(s.Owner)
+		if ret.Ships[key] == nil {
+			ret.Ships[key] = make(map[string]*raw_ship)
+		}
+		ret.Ships[key][strconv.Itoa(s.Id)] = ship
+	}
+
+	for _, p := range f.Planets {
+
+		planet := &raw_planet{
+			CurrentProduction: p.CurrentProduction,
+			DockedShips: append([]int{}, p.DockedShips...),		// Not nil, which would be null in the JSON
+			Health: p.HP,
+			Id: p.Id,
+			RemainingProduction: p.RemainingProduction,
+		}
+
+		if p.Owner != -1 {
+			owner := p.Owner
+			planet.Owner = &owner
+		}
+
+		ret.Planets[strconv.Itoa(p.Id)] = planet
+	}
+
+	for _, e := range f.Events {
+		ret.Events = append(ret.Events, unconvert_event(e))
+	}
+
+	return ret
+}
+
+func unconvert_entity(e EntityRef) raw_entity {
+	ret := raw_entity{Id: e.Id, Type: e.Type}
+	if e.Owner != -1 {
+		owner := e.Owner
+		ret.Owner = &owner
+	}
+	return ret
+}
+
+func unconvert_event(e *Event) *raw_event {
+
+	ret := &raw_event{
+		Entity: unconvert_entity(e.Entity),
+		Event: string(e.Type),
+		Time: e.Time,
+		X: e.X,
+		Y: e.Y,
+	}
+
+	switch e.Type {
+
+	case ATTACK:
+		for _, target := range e.Targets {
+			ret.Targets = append(ret.Targets, unconvert_entity(target))
+			ret.TargetLocations = append(ret.TargetLocations, unconvert_entity(target))
+		}
+
+	case DESTROYED:
+		radius := e.Radius
+		ret.Radius = &radius
+
+	case SPAWNED:
+		planet := unconvert_entity(e.Planet)
+		planet_x, planet_y := e.PlanetX, e.PlanetY
+		ret.Planet, ret.PlanetX, ret.PlanetY = &planet, &planet_x, &planet_y
+	}
+
+	return ret
+}
+
+func unconvert_move(m *Move) *raw_move {
+
+	ret := &raw_move{
+		Owner: m.Owner,
+		QueueNumber: m.QueueNumber,
+		ShipId: m.ShipId,
+		Type: string(m.Type),
+	}
+
+	switch m.Type {
+	case THRUST:
+		magnitude, angle := m.Magnitude, m.Angle
+		ret.Magnitude, ret.Angle = &magnitude, &angle
+	case DOCK:
+		planet_id := m.PlanetId
+		ret.PlanetId = &planet_id
+	}
+
+	return ret
+}
@@ -0,0 +1,61 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"./zstd"
+)
+
+var zstd_magic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// Load reads a .hlt file. These are normally zstd-compressed JSON, but plain JSON is accepted
+// too (one of our reference replays is stored that way).
+
+func Load(filename string) (*Replay, error) {
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ret, err := Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return ret, nil
+}
+
+func Decode(r io.Reader) (*Replay, error) {
+
+	buffered := bufio.NewReader(r)
+
+	magic, err := buffered.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("Decode(): couldn't read header: %v", err)
+	}
+
+	var source io.Reader = buffered
+
+	if bytes.Equal(magic, zstd_magic) {
+		source = zstd.NewReader(buffered)
+	}
+
+	raw := new(raw_replay)
+
+	err = json.NewDecoder(source).Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("Decode(): %v", err)
+	}
+
+	if len(raw.Frames) == 0 {
+		return nil, fmt.Errorf("Decode(): replay has no frames")
+	}
+
+	return raw.convert(), nil
+}
@@ -0,0 +1,201 @@
+package replay
+
+import (
+	"fmt"
+
+	hal "../core"
+)
+
+// Typed versions of what's in a .hlt replay file. The JSON itself is
+// awkward (maps keyed by stringified IDs, etc) so see raw.go for that.
+
+type Replay struct {
+	Version				int
+	EngineVersion		string
+	MapGenerator		string
+	Seed				int64
+	Width				int
+	Height				int
+	NumPlayers			int
+	PlayerNames			[]string
+	Constants			Constants
+	Planets				[]*PlanetInfo		// Static planet info, indexed by planet ID
+	Frames				[]*Frame			// Frames[n] is what the bots were sent on turn n (and, for n == 0, at init)
+	Moves				[][]*Move			// Moves[n] is what the bots sent on turn n
+	Stats				[]*PlayerStats		// Indexed by player ID
+}
+
+type Constants struct {
+	AdditionalProductivity	int			`json:"ADDITIONAL_PRODUCTIVITY"`
+	BaseProductivity		int			`json:"BASE_PRODUCTIVITY"`
+	BaseShipHealth			int			`json:"BASE_SHIP_HEALTH"`
+	DockedShipRegeneration	int			`json:"DOCKED_SHIP_REGENERATION"`
+	DockRadius				float64		`json:"DOCK_RADIUS"`
+	DockTurns				int			`json:"DOCK_TURNS"`
+	Drag					float64		`json:"DRAG"`
+	ExplosionRadius			float64		`json:"EXPLOSION_RADIUS"`
+	ExtraPlanets			int			`json:"EXTRA_PLANETS"`
+	InfiniteResources		bool		`json:"INFINITE_RESOURCES"`
+	MaxAcceleration			float64		`json:"MAX_ACCELERATION"`
+	MaxShipHealth			int			`json:"MAX_SHIP_HEALTH"`
+	MaxSpeed				float64		`json:"MAX_SPEED"`
+	MaxTurns				int			`json:"MAX_TURNS"`
+	PlanetsPerPlayer		int			`json:"PLANETS_PER_PLAYER"`
+	ProductionPerShip		int			`json:"PRODUCTION_PER_SHIP"`
+	ResourcesPerRadius		int			`json:"RESOURCES_PER_RADIUS"`
+	ShipsPerPlayer			int			`json:"SHIPS_PER_PLAYER"`
+	ShipRadius				float64		`json:"SHIP_RADIUS"`
+	SpawnRadius				float64		`json:"SPAWN_RADIUS"`
+	WeaponCooldown			int			`json:"WEAPON_COOLDOWN"`
+	WeaponDamage			int			`json:"WEAPON_DAMAGE"`
+	WeaponRadius			float64		`json:"WEAPON_RADIUS"`
+}
+
+type PlanetInfo struct {
+	Id					int
+	X					float64
+	Y					float64
+	Radius				float64
+	DockingSpots		int
+	Health				int					// Starting health
+	Production			int					// Starting resources
+}
+
+type Frame struct {
+	Ships				[]*Ship				// Sorted by ID
+	Planets				[]*Planet			// Sorted by ID. Destroyed planets are absent.
+	Events				[]*Event
+}
+
+type Ship struct {
+	Id					int
+	Owner				int
+	X					float64
+	Y					float64
+	VelX				float64
+	VelY				float64
+	HP					int
+	Cooldown			int
+	DockedStatus		hal.DockedStatus
+	DockedPlanet		int					// -1 if undocked
+	TurnsLeft			int					// Only for DOCKING and UNDOCKING ships
+}
+
+type Planet struct {
+	Id					int
+	HP					int
+	Owner				int					// -1 if not owned
+	DockedShips			[]int
+	CurrentProduction	int
+	RemainingProduction	int
+}
+
+type EventType string
+
+const (
+	ATTACK EventType = "attack"
+	DESTROYED EventType = "destroyed"
+	SPAWNED EventType = "spawned"
+)
+
+type EntityRef struct {
+	Type				string				// "ship" or "planet"
+	Id					int
+	Owner				int					// -1 for planets
+}
+
+type Event struct {
+	Type				EventType
+	Time				float64
+	X					float64
+	Y					float64
+	Entity				EntityRef
+	Targets				[]EntityRef			// ATTACK only
+	Radius				float64				// DESTROYED only
+	Planet				EntityRef			// SPAWNED only
+	PlanetX				float64				// SPAWNED only
+	PlanetY				float64				// SPAWNED only
+}
+
+type MoveType string
+
+const (
+	THRUST MoveType = "thrust"
+	DOCK MoveType = "dock"
+	UNDOCK MoveType = "undock"
+)
+
+type Move struct {
+	Type				MoveType
+	Owner				int
+	ShipId				int
+	Magnitude			int					// THRUST only
+	Angle				int					// THRUST only; may include an angle message (> 360)
+	PlanetId			int					// DOCK only
+	QueueNumber			int
+}
+
+type PlayerStats struct {
+	Rank					int
+	LastFrameAlive			int
+	TotalShipCount			int
+	DamageDealt				int
+	InitResponseTime		float64
+	AverageResponseTime		float64
+	MaxResponseTime			float64
+}
+
+// ---------------------------------------
+
+func (self *Frame) GetShip(sid int) (*Ship, bool) {
+	for _, ship := range self.Ships {
+		if ship.Id == sid {
+			return ship, true
+		}
+	}
+	return nil, false
+}
+
+func (self *Frame) GetPlanet(plid int) (*Planet, bool) {
+	for _, planet := range self.Planets {
+		if planet.Id == plid {
+			return planet, true
+		}
+	}
+	return nil, false
+}
+
+func (self *Frame) ShipsOwnedBy(pid int) []*Ship {
+	var ret []*Ship
+	for _, ship := range self.Ships {
+		if ship.Owner == pid {
+			ret = append(ret, ship)
+		}
+	}
+	return ret
+}
+
+func (self *Move) String() string {
+	switch self.Type {
+	case THRUST:
+		return fmt.Sprintf("t %d %d %d", self.ShipId, self.Magnitude, self.Angle)
+	case DOCK:
+		return fmt.Sprintf("d %d %d", self.ShipId, self.PlanetId)
+	case UNDOCK:
+		return fmt.Sprintf("u %d", self.ShipId)
+	}
+	return "?"
+}
+
+func (self *Replay) MovesBy(turn, pid int) []*Move {
+	var ret []*Move
+	if turn < 0 || turn >= len(self.Moves) {
+		return nil
+	}
+	for _, move := range self.Moves[turn] {
+		if move.Owner == pid {
+			ret = append(ret, move)
+		}
+	}
+	return ret
+}
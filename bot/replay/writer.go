@@ -0,0 +1,108 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"./zstd"
+)
+
+// A Writer builds a Replay a frame at a time, for a match harness to save at the end. The
+// order of calls is: AddFrame() for the initial frame, then for each turn AddMoves() with
+// what the bots sent and AddFrame() with the result, then Finish() with the stats.
+
+const (
+	REPLAY_VERSION = 31						// As in the reference replays
+	ENGINE_VERSION = "gohalite2 local"
+)
+
+type Writer struct {
+	replay				*Replay
+}
+
+// NewWriter starts a replay. Frames, Moves and Stats in the header are ignored, and the
+// versions are filled in if empty.
+
+func NewWriter(header Replay) *Writer {
+
+	r := header
+	r.Frames, r.Moves, r.Stats = nil, nil, nil
+
+	if r.Version == 0 {
+		r.Version = REPLAY_VERSION
+	}
+	if r.EngineVersion == "" {
+		r.EngineVersion = ENGINE_VERSION
+	}
+
+	return &Writer{replay: &r}
+}
+
+func (self *Writer) AddFrame(frame *Frame) {
+	self.replay.Frames = append(self.replay.Frames, frame)
+}
+
+func (self *Writer) AddMoves(moves []*Move) {
+	self.replay.Moves = append(self.replay.Moves, moves)
+}
+
+func (self *Writer) Finish(stats []*PlayerStats) {
+	self.replay.Stats = stats
+}
+
+func (self *Writer) Replay() *Replay {
+	return self.replay
+}
+
+func (self *Writer) Save(filename string) error {
+	return Save(filename, self.replay)
+}
+
+// ---------------------------------------
+
+// Save writes a replay as a zstd-compressed .hlt file.
+
+func Save(filename string, replay *Replay) error {
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = Encode(f, replay)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return f.Close()
+}
+
+func Encode(w io.Writer, replay *Replay) error {
+
+	if len(replay.Frames) == 0 {
+		return fmt.Errorf("Encode(): replay has no frames")
+	}
+
+	if len(replay.Moves) != len(replay.Frames) - 1 {
+		return fmt.Errorf("Encode(): %d frames but %d turns of moves", len(replay.Frames), len(replay.Moves))
+	}
+
+	buffered := bufio.NewWriter(w)
+	compressor := zstd.NewWriter(buffered)
+
+	err := json.NewEncoder(compressor).Encode(unconvert_replay(replay))
+	if err != nil {
+		return fmt.Errorf("Encode(): %v", err)
+	}
+
+	err = compressor.Close()
+	if err != nil {
+		return err
+	}
+
+	return buffered.Flush()
+}
@@ -0,0 +1,428 @@
+package zstd
+
+// Not part of the standard library's package: a small compressor, so we can save replays that
+// anything reading .hlt files (including the official visualiser) will accept. It writes a
+// single frame with a content checksum. Blocks are compressed with a greedy LZ77 match finder,
+// raw literals, and the predefined FSE tables for the sequences (RFC 3.1.1.3.2.2); a block
+// that doesn't shrink is stored raw. Simple, but JSON is repetitive enough for this to do well.
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+)
+
+const (
+	writerBlockSize   = 128 << 10 // The largest block allowed
+	writerWindowLog   = 22
+	writerWindowSize  = 1 << writerWindowLog
+	writerHashLog     = 17
+	writerMinMatch    = 4
+	writerFrameHeader = 1 << 2 // Frame_Header_Descriptor: unknown content size, not single segment, has checksum
+)
+
+// Writer implements [io.WriteCloser]. Close must be called to finish the frame; it doesn't
+// close the underlying writer.
+type Writer struct {
+	w           io.Writer
+	hist        []byte // Recent input, including the block being filled
+	histBase    int64  // Position in the whole input of hist[0]
+	blockStart  int    // Index in hist of the block being filled
+	table       []int64
+	hash        xxhash64
+	wroteHeader bool
+	err         error
+
+	// Scratch space for a block.
+	literals []byte
+	seqs     []writerSeq
+	out      []byte
+}
+
+type writerSeq struct {
+	litLen   uint32
+	matchLen uint32
+	offset   uint32
+}
+
+func NewWriter(w io.Writer) *Writer {
+	ret := &Writer{w: w, table: make([]int64, 1<<writerHashLog)}
+	for i := range ret.table {
+		ret.table[i] = -1
+	}
+	ret.hash.reset()
+	return ret
+}
+
+func (zw *Writer) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 && zw.err == nil {
+		room := writerBlockSize - (len(zw.hist) - zw.blockStart)
+		if room == 0 {
+			zw.flush(false)
+			continue
+		}
+		c := len(p)
+		if c > room {
+			c = room
+		}
+		zw.hist = append(zw.hist, p[:c]...)
+		zw.hash.update(p[:c])
+		p = p[c:]
+		n += c
+	}
+	return n, zw.err
+}
+
+func (zw *Writer) Close() error {
+	zw.flush(true)
+	if zw.err == nil {
+		var checksum [4]byte
+		binary.LittleEndian.PutUint32(checksum[:], uint32(zw.hash.digest()))
+		_, zw.err = zw.w.Write(checksum[:])
+	}
+	return zw.err
+}
+
+func (zw *Writer) write(b []byte) {
+	if zw.err == nil {
+		_, zw.err = zw.w.Write(b)
+	}
+}
+
+// flush writes the block being filled, then drops history that's out of the window.
+func (zw *Writer) flush(last bool) {
+
+	if zw.err != nil {
+		return
+	}
+
+	if !zw.wroteHeader {
+		var header [6]byte
+		binary.LittleEndian.PutUint32(header[:], 0xfd2fb528)
+		header[4] = writerFrameHeader
+		header[5] = (writerWindowLog - 10) << 3
+		zw.write(header[:])
+		zw.wroteHeader = true
+	}
+
+	block := zw.hist[zw.blockStart:]
+
+	blockType := uint32(0) // Raw
+	payload := block
+
+	if compressed := zw.compressBlock(); compressed != nil && len(compressed) < len(block) {
+		blockType = 2
+		payload = compressed
+	}
+
+	h := uint32(len(payload))<<3 | blockType<<1
+	if last {
+		h |= 1
+	}
+
+	zw.write([]byte{byte(h), byte(h >> 8), byte(h >> 16)})
+	zw.write(payload)
+
+	zw.blockStart = len(zw.hist)
+
+	if drop := len(zw.hist) - writerWindowSize; drop >= writerWindowSize {
+		zw.hist = append(zw.hist[:0], zw.hist[drop:]...)
+		zw.histBase += int64(drop)
+		zw.blockStart -= drop
+	}
+}
+
+func writerHash(b []byte) uint32 {
+	return (binary.LittleEndian.Uint32(b) * 2654435761) >> (32 - writerHashLog)
+}
+
+// compressBlock finds matches for the block being filled and encodes them. Returns nil if
+// there's nothing worth compressing.
+func (zw *Writer) compressBlock() []byte {
+
+	hist := zw.hist
+	end := len(hist)
+
+	zw.literals = zw.literals[:0]
+	zw.seqs = zw.seqs[:0]
+
+	litStart := zw.blockStart
+
+	for i := zw.blockStart; i+writerMinMatch <= end; {
+
+		h := writerHash(hist[i:])
+		cand := zw.table[h] - zw.histBase
+		zw.table[h] = zw.histBase + int64(i)
+
+		if cand < 0 || int64(i)-cand > writerWindowSize || binary.LittleEndian.Uint32(hist[cand:]) != binary.LittleEndian.Uint32(hist[i:]) {
+			i++
+			continue
+		}
+
+		n := writerMinMatch
+		for i+n < end && hist[int(cand)+n] == hist[i+n] {
+			n++
+		}
+
+		zw.literals = append(zw.literals, hist[litStart:i]...)
+		zw.seqs = append(zw.seqs, writerSeq{
+			litLen:   uint32(i - litStart),
+			matchLen: uint32(n),
+			offset:   uint32(int64(i) - cand),
+		})
+
+		for j := i + 1; j < i+n && j+writerMinMatch <= end; j++ {
+			zw.table[writerHash(hist[j:])] = zw.histBase + int64(j)
+		}
+
+		i += n
+		litStart = i
+	}
+
+	if len(zw.seqs) == 0 {
+		return nil
+	}
+
+	zw.literals = append(zw.literals, hist[litStart:end]...)
+
+	// Literals section: raw, with the 3 byte header (Size_Format 11). RFC 3.1.1.3.1.
+
+	out := zw.out[:0]
+	size := len(zw.literals)
+	out = append(out, byte(3<<2|(size&0xf)<<4), byte(size>>4), byte(size>>12))
+	out = append(out, zw.literals...)
+
+	// Sequences section header. RFC 3.1.1.3.2.1.
+
+	nseq := len(zw.seqs)
+	switch {
+	case nseq < 128:
+		out = append(out, byte(nseq))
+	case nseq < 0x7f00:
+		out = append(out, byte(nseq>>8+128), byte(nseq))
+	default:
+		out = append(out, 0xff, byte(nseq-0x7f00), byte((nseq-0x7f00)>>8))
+	}
+
+	out = append(out, 0) // Predefined tables for all three
+
+	out = zw.encodeSequences(out)
+
+	zw.out = out
+	return out
+}
+
+// encodeSequences writes the sequences bitstream. The decoder reads it backwards, so the last
+// sequence is written first. RFC 3.1.1.3.2.2 and 4.1.
+func (zw *Writer) encodeSequences(out []byte) []byte {
+
+	initWriterTables()
+
+	var bw bitWriter
+	bw.out = out
+
+	seqs := zw.seqs
+	n := len(seqs) - 1
+
+	llCode, llBits, llExtra := literalLengthCode(seqs[n].litLen)
+	mlCode, mlBits, mlExtra := matchLengthCode(seqs[n].matchLen)
+	ofCode, ofBits, ofExtra := offsetCode(seqs[n].offset)
+
+	llState := writerLiteralTable.initState(llCode)
+	mlState := writerMatchTable.initState(mlCode)
+	ofState := writerOffsetTable.initState(ofCode)
+
+	bw.addBits(llExtra, llBits)
+	bw.addBits(mlExtra, mlBits)
+	bw.addBits(ofExtra, ofBits)
+
+	for i := n - 1; i >= 0; i-- {
+
+		llCode, llBits, llExtra = literalLengthCode(seqs[i].litLen)
+		mlCode, mlBits, mlExtra = matchLengthCode(seqs[i].matchLen)
+		ofCode, ofBits, ofExtra = offsetCode(seqs[i].offset)
+
+		writerOffsetTable.encode(&bw, &ofState, ofCode)
+		writerMatchTable.encode(&bw, &mlState, mlCode)
+		writerLiteralTable.encode(&bw, &llState, llCode)
+
+		bw.addBits(llExtra, llBits)
+		bw.addBits(mlExtra, mlBits)
+		bw.addBits(ofExtra, ofBits)
+	}
+
+	bw.addBits(mlState, writerMatchTable.tableLog)
+	bw.addBits(ofState, writerOffsetTable.tableLog)
+	bw.addBits(llState, writerLiteralTable.tableLog)
+
+	return bw.close()
+}
+
+func literalLengthCode(v uint32) (code uint8, nbits uint8, extra uint32) {
+	if v < literalLengthOffset {
+		return uint8(v), 0, 0
+	}
+	i := len(literalLengthBase) - 1
+	for literalLengthBase[i]&0xffffff > v {
+		i--
+	}
+	base := literalLengthBase[i]
+	return uint8(i + literalLengthOffset), uint8(base >> 24), v - base&0xffffff
+}
+
+func matchLengthCode(v uint32) (code uint8, nbits uint8, extra uint32) {
+	if v < matchLengthOffset+3 {
+		return uint8(v - 3), 0, 0
+	}
+	i := len(matchLengthBase) - 1
+	for matchLengthBase[i]&0xffffff > v {
+		i--
+	}
+	base := matchLengthBase[i]
+	return uint8(i + matchLengthOffset), uint8(base >> 24), v - base&0xffffff
+}
+
+// offsetCode uses Offset_Value = offset + 3, i.e. never a repeated offset.
+func offsetCode(offset uint32) (code uint8, nbits uint8, extra uint32) {
+	v := offset + 3
+	code = uint8(bits.Len32(v) - 1)
+	return code, code, v - 1<<code
+}
+
+// bitWriter is the forward half of the backward bitstream. RFC 4.1.
+type bitWriter struct {
+	out   []byte
+	acc   uint64
+	nbits uint8
+}
+
+func (bw *bitWriter) addBits(v uint32, n uint8) {
+	bw.acc |= uint64(v&(1<<n-1)) << bw.nbits
+	bw.nbits += n
+	for bw.nbits >= 8 {
+		bw.out = append(bw.out, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nbits -= 8
+	}
+}
+
+// close adds the end mark (a 1 bit) and pads to a byte.
+func (bw *bitWriter) close() []byte {
+	bw.addBits(1, 1)
+	if bw.nbits > 0 {
+		bw.out = append(bw.out, byte(bw.acc))
+	}
+	return bw.out
+}
+
+// fseEncTable is an FSE encoding table, built as in the reference FSE_buildCTable().
+type fseEncTable struct {
+	tableLog    uint8
+	stateTable  []uint32
+	deltaNbBits []uint32
+	deltaFind   []int32
+}
+
+func newFSEEncTable(norm []int16, tableLog uint8) *fseEncTable {
+
+	tableSize := 1 << tableLog
+	mask := tableSize - 1
+	highThreshold := tableSize - 1
+
+	symbols := make([]int, tableSize)
+	cumul := make([]int, len(norm)+1)
+
+	for s, count := range norm {
+		if count == -1 {
+			cumul[s+1] = cumul[s] + 1
+			symbols[highThreshold] = s
+			highThreshold--
+		} else {
+			cumul[s+1] = cumul[s] + int(count)
+		}
+	}
+
+	step := tableSize>>1 + tableSize>>3 + 3
+	pos := 0
+	for s, count := range norm {
+		for i := 0; i < int(count); i++ {
+			symbols[pos] = s
+			pos = (pos + step) & mask
+			for pos > highThreshold {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+
+	t := &fseEncTable{
+		tableLog:    tableLog,
+		stateTable:  make([]uint32, tableSize),
+		deltaNbBits: make([]uint32, len(norm)),
+		deltaFind:   make([]int32, len(norm)),
+	}
+
+	for u, s := range symbols {
+		t.stateTable[cumul[s]] = uint32(tableSize + u)
+		cumul[s]++
+	}
+
+	total := 0
+	for s, count := range norm {
+		switch count {
+		case 0:
+		case -1, 1:
+			t.deltaNbBits[s] = uint32(tableLog)<<16 - uint32(tableSize)
+			t.deltaFind[s] = int32(total - 1)
+			total++
+		default:
+			maxBitsOut := uint32(tableLog) - uint32(bits.Len32(uint32(count-1))-1)
+			minStatePlus := uint32(count) << maxBitsOut
+			t.deltaNbBits[s] = maxBitsOut<<16 - minStatePlus
+			t.deltaFind[s] = int32(total - int(count))
+			total += int(count)
+		}
+	}
+
+	return t
+}
+
+func (t *fseEncTable) initState(symbol uint8) uint32 {
+	nbBitsOut := (t.deltaNbBits[symbol] + 1<<15) >> 16
+	value := nbBitsOut<<16 - t.deltaNbBits[symbol]
+	return t.stateTable[int32(value>>nbBitsOut)+t.deltaFind[symbol]]
+}
+
+func (t *fseEncTable) encode(bw *bitWriter, state *uint32, symbol uint8) {
+	nbBitsOut := (*state + t.deltaNbBits[symbol]) >> 16
+	bw.addBits(*state, uint8(nbBitsOut))
+	*state = t.stateTable[int32(*state>>nbBitsOut)+t.deltaFind[symbol]]
+}
+
+// The predefined distributions. RFC 3.1.1.3.2.2.
+var (
+	writerLiteralTable *fseEncTable
+	writerMatchTable   *fseEncTable
+	writerOffsetTable  *fseEncTable
+)
+
+func initWriterTables() {
+	if writerLiteralTable != nil {
+		return
+	}
+	writerLiteralTable = newFSEEncTable([]int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}, 6)
+	writerMatchTable = newFSEEncTable([]int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}, 6)
+	writerOffsetTable = newFSEEncTable([]int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}, 5)
+}
@@ -0,0 +1,86 @@
+package zstd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// roundTrip compresses b with Writer, writing it in chunks of the given size, and decompresses
+// the result with Reader.
+func roundTrip(t *testing.T, b []byte, chunk int) []byte {
+	t.Helper()
+
+	var compressed bytes.Buffer
+	zw := NewWriter(&compressed)
+	for p := b; len(p) > 0; {
+		c := chunk
+		if c > len(p) {
+			c = len(p)
+		}
+		if _, err := zw.Write(p[:c]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		p = p[c:]
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got, err := io.ReadAll(NewReader(&compressed))
+	if err != nil {
+		t.Fatalf("decompressing %d bytes (%d compressed): %v", len(b), compressed.Len(), err)
+	}
+	return got
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	random := make([]byte, 300<<10)
+	rng.Read(random)
+
+	var json bytes.Buffer // Like a replay: long and repetitive
+	for i := 0; json.Len() < 1<<20; i++ {
+		fmt.Fprintf(&json, `{"id":%d,"x":%.4f,"y":%.4f,"health":255,"vel":{"x":0,"y":0}},`, i%400, rng.Float64()*240, rng.Float64()*160)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"one byte", []byte("x")},
+		{"short", []byte("hello, hello, hello, world")},
+		{"zeros", make([]byte, 200<<10)},
+		{"random", random},
+		{"json", json.Bytes()},
+		{"mixed", append(append([]byte{}, json.Bytes()[:100<<10]...), random[:50<<10]...)},
+	}
+
+	for _, test := range tests {
+		for _, chunk := range []int{1 << 30, 1000} {
+			got := roundTrip(t, test.data, chunk)
+			if !bytes.Equal(got, test.data) {
+				t.Errorf("%s, chunks of %d: got %d bytes back from %d, or different ones", test.name, chunk, len(got), len(test.data))
+			}
+		}
+	}
+}
+
+func TestWriterCompresses(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"type":"thrust","shipId":12,"magnitude":7,"angle":90},`), 10000)
+
+	var compressed bytes.Buffer
+	zw := NewWriter(&compressed)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if compressed.Len() > len(data)/10 {
+		t.Errorf("%d bytes compressed to %d", len(data), compressed.Len())
+	}
+}
@@ -0,0 +1,72 @@
+package viz
+
+import (
+	"sort"
+
+	ai "../ai"
+	hal "../core"
+)
+
+// An Overlay is our own AI's view of a turn, drawn on top of the replay frame: what each pilot
+// was aiming for, the waypoints navigation used to get round things, and the opening's docks.
+
+type PilotInfo struct {
+	Sid					int
+	X					float64
+	Y					float64
+	Target				*hal.Point			// nil if no target
+	TargetName			string
+	Waypoints			[]*hal.Point
+	Order				string
+	NavStack			[]string
+}
+
+type Overlay struct {
+	Pid					int
+	Turn				int
+	Strategy			string
+	ThreatRange			float64
+	Pilots				[]*PilotInfo		// Sorted by ship ID
+	Docks				[]*hal.Port
+}
+
+// NewOverlay collects the overlay from an Overmind that has just run Step().
+
+func NewOverlay(o *ai.Overmind) *Overlay {
+
+	ret := &Overlay{
+		Pid: o.Game.Pid(),
+		Turn: o.Game.Turn(),
+		ThreatRange: o.Game.Params().ThreatRange,
+		Docks: o.OpeningDocks,
+	}
+
+	if o.Strategy != nil {
+		ret.Strategy = o.Strategy.Name()
+	}
+
+	for _, pilot := range o.Pilots {
+
+		info := &PilotInfo{
+			Sid: pilot.Id,
+			X: pilot.X,
+			Y: pilot.Y,
+			Waypoints: pilot.Waypoints,
+			Order: o.Game.CurrentOrder(pilot.Ship).String(),
+			NavStack: pilot.NavStack,
+		}
+
+		if pilot.HasTarget() {
+			info.Target = &hal.Point{X: pilot.Target.GetX(), Y: pilot.Target.GetY()}
+			info.TargetName = pilot.Target.String()
+		}
+
+		ret.Pilots = append(ret.Pilots, info)
+	}
+
+	sort.Slice(ret.Pilots, func(a, b int) bool {
+		return ret.Pilots[a].Sid < ret.Pilots[b].Sid
+	})
+
+	return ret
+}
@@ -0,0 +1,204 @@
+package viz
+
+// Draws a replay frame as SVG. Coordinates in the SVG are game units (the viewBox is the map),
+// so the output can be zoomed freely. Hovering over things shows details, including each
+// pilot's nav stack when there's an overlay.
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+
+	hal "../core"
+	rep "../replay"
+)
+
+var PLAYER_COLOURS = []string{"#e04040", "#4080ff", "#40c040", "#ffa020"}
+
+const NEUTRAL_COLOUR = "#808080"
+
+var docked_names = map[hal.DockedStatus]string{
+	hal.UNDOCKED: "undocked",
+	hal.DOCKING: "docking",
+	hal.DOCKED: "docked",
+	hal.UNDOCKING: "undocking",
+}
+
+type Options struct {
+	Scale				float64			// Pixels per game unit
+	Weapons				bool			// Draw the range at which each mobile ship can hit another
+	Threats				bool			// Draw each planet's threat range (see core.Params)
+	Moves				bool			// Draw the thrusts sent this turn
+}
+
+func DefaultOptions() Options {
+	return Options{Scale: 3, Weapons: true, Threats: true, Moves: true}
+}
+
+func colour(owner int) string {
+	if owner < 0 || owner >= len(PLAYER_COLOURS) {
+		return NEUTRAL_COLOUR
+	}
+	return PLAYER_COLOURS[owner]
+}
+
+// SVG draws frame <turn> of the replay, with the overlay (which may be nil) on top.
+
+func SVG(w io.Writer, r *rep.Replay, turn int, overlay *Overlay, opts Options) error {
+
+	if turn < 0 || turn >= len(r.Frames) {
+		return fmt.Errorf("SVG(): turn %d not in replay (%d frames)", turn, len(r.Frames))
+	}
+
+	frame := r.Frames[turn]
+	out := bufio.NewWriter(w)
+
+	p := func(format_string string, args ...interface{}) {
+		fmt.Fprintf(out, format_string, args...)
+	}
+
+	p("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" viewBox=\"0 0 %d %d\">\n",
+		float64(r.Width) * opts.Scale, float64(r.Height) * opts.Scale, r.Width, r.Height)
+	p("<rect width=\"%d\" height=\"%d\" fill=\"#000\"/>\n", r.Width, r.Height)
+
+	// Threat ranges, under everything else...
+
+	if opts.Threats {
+		threat_range := hal.DefaultParams().ThreatRange
+		if overlay != nil {
+			threat_range = overlay.ThreatRange
+		}
+		for _, planet := range frame.Planets {
+			info := r.Planets[planet.Id]
+			p("<circle cx=\"%.2f\" cy=\"%.2f\" r=\"%.2f\" fill=\"none\" stroke=\"#333\" stroke-width=\"0.3\" stroke-dasharray=\"1,1\"/>\n",
+				info.X, info.Y, info.Radius + threat_range)
+		}
+	}
+
+	// Planets...
+
+	for _, planet := range frame.Planets {
+		info := r.Planets[planet.Id]
+		p("<circle cx=\"%.2f\" cy=\"%.2f\" r=\"%.2f\" fill=\"%s\" fill-opacity=\"0.5\" stroke=\"%s\" stroke-width=\"0.3\">",
+			info.X, info.Y, info.Radius, colour(planet.Owner), colour(planet.Owner))
+		p("<title>%s</title></circle>\n", esc(fmt.Sprintf("Planet %d: owner %d, hp %d, docked %v (spots %d)",
+			planet.Id, planet.Owner, planet.HP, planet.DockedShips, info.DockingSpots)))
+		p("<text x=\"%.2f\" y=\"%.2f\" font-size=\"3\" fill=\"#fff\" text-anchor=\"middle\">%d</text>\n",
+			info.X, info.Y + 1, planet.Id)
+	}
+
+	// Weapon ranges...
+
+	if opts.Weapons {
+		for _, ship := range frame.Ships {
+			if ship.DockedStatus == hal.UNDOCKED {
+				p("<circle cx=\"%.2f\" cy=\"%.2f\" r=\"%.2f\" fill=\"%s\" fill-opacity=\"0.06\" stroke=\"none\"/>\n",
+					ship.X, ship.Y, hal.WEAPON_RANGE + 2 * hal.SHIP_RADIUS, colour(ship.Owner))
+			}
+		}
+	}
+
+	// This turn's thrusts...
+
+	if opts.Moves && turn < len(r.Moves) {
+		for _, move := range r.Moves[turn] {
+			ship, ok := frame.GetShip(move.ShipId)
+			if ok == false || move.Type != rep.THRUST || move.Magnitude == 0 {
+				continue
+			}
+			x2, y2 := hal.Projection(ship.X, ship.Y, float64(move.Magnitude), move.Angle % 360)
+			p("<line x1=\"%.2f\" y1=\"%.2f\" x2=\"%.2f\" y2=\"%.2f\" stroke=\"%s\" stroke-width=\"0.3\"/>\n",
+				ship.X, ship.Y, x2, y2, colour(ship.Owner))
+		}
+	}
+
+	// Ships. Docked (or docking) ships are hollow...
+
+	for _, ship := range frame.Ships {
+		fill := colour(ship.Owner)
+		if ship.DockedStatus != hal.UNDOCKED {
+			fill = "none"
+		}
+		p("<circle cx=\"%.2f\" cy=\"%.2f\" r=\"%.2f\" fill=\"%s\" stroke=\"%s\" stroke-width=\"0.25\">",
+			ship.X, ship.Y, hal.SHIP_RADIUS, fill, colour(ship.Owner))
+		p("<title>%s</title></circle>\n", esc(fmt.Sprintf("Ship %d: owner %d, hp %d, %v [%.2f, %.2f]",
+			ship.Id, ship.Owner, ship.HP, docked_names[ship.DockedStatus], ship.X, ship.Y)))
+	}
+
+	// Events that happened on the way to this frame...
+
+	for _, ev := range frame.Events {
+		switch ev.Type {
+		case rep.ATTACK:
+			for _, target := range ev.Targets {
+				if ship, ok := frame.GetShip(target.Id); ok {
+					p("<line x1=\"%.2f\" y1=\"%.2f\" x2=\"%.2f\" y2=\"%.2f\" stroke=\"#ff0\" stroke-width=\"0.15\" stroke-opacity=\"0.6\"/>\n",
+						ev.X, ev.Y, ship.X, ship.Y)
+				}
+			}
+		case rep.DESTROYED:
+			p("<circle cx=\"%.2f\" cy=\"%.2f\" r=\"%.2f\" fill=\"none\" stroke=\"#fff\" stroke-width=\"0.2\" stroke-dasharray=\"0.5,0.5\"/>\n",
+				ev.X, ev.Y, ev.Radius + 1)
+		}
+	}
+
+	if overlay != nil {
+		svg_overlay(p, overlay)
+	}
+
+	title := fmt.Sprintf("Turn %d", turn)
+	if overlay != nil {
+		title += fmt.Sprintf(" - player %d - %s", overlay.Pid, overlay.Strategy)
+	}
+
+	p("<text x=\"2\" y=\"5\" font-size=\"4\" fill=\"#fff\">%s</text>\n", esc(title))
+	p("</svg>\n")
+
+	return out.Flush()
+}
+
+func svg_overlay(p func(string, ...interface{}), overlay *Overlay) {
+
+	for _, dock := range overlay.Docks {
+		p("<rect x=\"%.2f\" y=\"%.2f\" width=\"0.8\" height=\"0.8\" fill=\"#ff0\" transform=\"rotate(45 %.2f %.2f)\"><title>%s</title></rect>\n",
+			dock.X - 0.4, dock.Y - 0.4, dock.X, dock.Y, esc(fmt.Sprintf("Opening dock for planet %d", dock.PlanetID)))
+	}
+
+	for _, pilot := range overlay.Pilots {
+
+		var points []string
+
+		points = append(points, fmt.Sprintf("%.2f,%.2f", pilot.X, pilot.Y))
+
+		for _, wp := range pilot.Waypoints {
+			points = append(points, fmt.Sprintf("%.2f,%.2f", wp.X, wp.Y))
+			p("<circle cx=\"%.2f\" cy=\"%.2f\" r=\"0.3\" fill=\"#0ff\"/>\n", wp.X, wp.Y)
+		}
+
+		if pilot.Target != nil {
+			points = append(points, fmt.Sprintf("%.2f,%.2f", pilot.Target.X, pilot.Target.Y))
+			p("<circle cx=\"%.2f\" cy=\"%.2f\" r=\"0.6\" fill=\"none\" stroke=\"#fff\" stroke-width=\"0.2\"/>\n",
+				pilot.Target.X, pilot.Target.Y)
+		}
+
+		if len(points) > 1 {
+			p("<polyline points=\"%s\" fill=\"none\" stroke=\"#fff\" stroke-width=\"0.15\" stroke-dasharray=\"0.6,0.4\"/>\n",
+				strings.Join(points, " "))
+		}
+
+		// An invisible, slightly larger circle over the ship carries the pilot's details...
+
+		details := []string{fmt.Sprintf("Pilot %d: order \"%s\", target %s", pilot.Sid, pilot.Order, pilot.TargetName)}
+		details = append(details, pilot.NavStack...)
+
+		p("<circle cx=\"%.2f\" cy=\"%.2f\" r=\"%.2f\" fill=\"#fff\" fill-opacity=\"0\"><title>%s</title></circle>\n",
+			pilot.X, pilot.Y, math.Max(1, hal.SHIP_RADIUS * 2), esc(strings.Join(details, "\n")))
+	}
+}
+
+func esc(s string) string {
+	return html.EscapeString(s)
+}
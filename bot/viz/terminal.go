@@ -0,0 +1,116 @@
+package viz
+
+// A rough text version of SVG(), for a quick look without leaving the terminal. Each cell is
+// twice as tall as it is wide, roughly as in most terminal fonts. Key:
+//
+//     0-3 . planet owned by that player, or nobody
+//     a-d   ship of player 0-3 (upper case if docked or docking)
+//     x     a pilot's target       + a nav waypoint       # an opening dock
+//
+// Ships are drawn over everything else; where things overlap only one shows. With an overlay,
+// each pilot's order, target and waypoints are listed below the map.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	hal "../core"
+	rep "../replay"
+)
+
+func Terminal(w io.Writer, r *rep.Replay, turn int, overlay *Overlay, cols int) error {
+
+	if turn < 0 || turn >= len(r.Frames) {
+		return fmt.Errorf("Terminal(): turn %d not in replay (%d frames)", turn, len(r.Frames))
+	}
+
+	frame := r.Frames[turn]
+	out := bufio.NewWriter(w)
+
+	cols = hal.Max(cols, 20)
+	rows := hal.Max(1, cols * r.Height / r.Width / 2)
+
+	cell_w := float64(r.Width) / float64(cols)
+	cell_h := float64(r.Height) / float64(rows)
+
+	grid := make([][]byte, rows)
+	for y := range grid {
+		grid[y] = []byte(strings.Repeat(" ", cols))
+	}
+
+	put := func(x, y float64, c byte) {
+		gx, gy := int(x / cell_w), int(y / cell_h)
+		if gx >= 0 && gx < cols && gy >= 0 && gy < rows {
+			grid[gy][gx] = c
+		}
+	}
+
+	for _, planet := range frame.Planets {
+
+		info := r.Planets[planet.Id]
+
+		c := byte('.')
+		if planet.Owner >= 0 {
+			c = byte('0' + planet.Owner)
+		}
+
+		for gy := 0; gy < rows; gy++ {
+			for gx := 0; gx < cols; gx++ {
+				cx, cy := (float64(gx) + 0.5) * cell_w, (float64(gy) + 0.5) * cell_h
+				if hal.Dist(cx, cy, info.X, info.Y) <= math.Max(info.Radius, cell_h / 2) {
+					grid[gy][gx] = c
+				}
+			}
+		}
+	}
+
+	if overlay != nil {
+		for _, dock := range overlay.Docks {
+			put(dock.X, dock.Y, '#')
+		}
+		for _, pilot := range overlay.Pilots {
+			for _, wp := range pilot.Waypoints {
+				put(wp.X, wp.Y, '+')
+			}
+			if pilot.Target != nil {
+				put(pilot.Target.X, pilot.Target.Y, 'x')
+			}
+		}
+	}
+
+	for _, ship := range frame.Ships {
+		c := byte('a' + ship.Owner)
+		if ship.DockedStatus != hal.UNDOCKED {
+			c = byte('A' + ship.Owner)
+		}
+		put(ship.X, ship.Y, c)
+	}
+
+	fmt.Fprintf(out, "Turn %d\n", turn)
+	fmt.Fprintf(out, "+%s+\n", strings.Repeat("-", cols))
+	for _, line := range grid {
+		fmt.Fprintf(out, "|%s|\n", line)
+	}
+	fmt.Fprintf(out, "+%s+\n", strings.Repeat("-", cols))
+
+	if overlay != nil {
+
+		fmt.Fprintf(out, "Player %d, strategy %s\n", overlay.Pid, overlay.Strategy)
+
+		for _, pilot := range overlay.Pilots {
+
+			fmt.Fprintf(out, "  ship %4d  [%6.1f, %6.1f]  %-16s  target %s", pilot.Sid, pilot.X, pilot.Y, pilot.Order, pilot.TargetName)
+
+			for _, wp := range pilot.Waypoints {
+				fmt.Fprintf(out, " via [%.1f, %.1f]", wp.X, wp.Y)
+			}
+
+			fmt.Fprintf(out, "\n")
+		}
+	}
+
+	return out.Flush()
+}